@@ -0,0 +1,77 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// ContentDiagnosticName is the name of the single ContentDiagnostic served per workspace.
+const ContentDiagnosticName = "default"
+
+// ContentDiagnosticEntry is a contentconfiguration that was considered for the
+// requested workspace.
+type ContentDiagnosticEntry struct {
+	// Name is the metadata.name of the contentconfiguration. It is empty if the
+	// whole source was excluded before its contentconfigurations were listed.
+	Name string `json:"name,omitempty"`
+
+	// Source is the kind of source the contentconfiguration was collected from,
+	// e.g. local, apiexport, provider or ancestor.
+	Source string `json:"source"`
+
+	// SourceName identifies the source, e.g. the APIExport name or workspace path.
+	SourceName string `json:"sourceName,omitempty"`
+
+	// Cluster is the logical cluster the contentconfiguration was read from.
+	Cluster string `json:"cluster,omitempty"`
+
+	// Included is true if the contentconfiguration is served for the workspace.
+	Included bool `json:"included"`
+
+	// Reason explains why the contentconfiguration is excluded.
+	Reason string `json:"reason,omitempty"`
+}
+
+// ContentDiagnosticSpec defines the desired state of ContentDiagnostic.
+type ContentDiagnosticSpec struct {
+
+	// Entries are all contentconfigurations considered for the workspace, the
+	// included ones first in the order they are served.
+	Entries []ContentDiagnosticEntry `json:"entries,omitempty"`
+}
+
+// ContentDiagnosticStatus defines the observed state of ContentDiagnostic.
+type ContentDiagnosticStatus struct {
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+
+// ContentDiagnostic is the Schema for the contentdiagnostics API.
+type ContentDiagnostic struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ContentDiagnosticSpec   `json:"spec,omitempty"`
+	Status ContentDiagnosticStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ContentDiagnosticList contains a list of ContentDiagnostic.
+type ContentDiagnosticList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ContentDiagnostic `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(func(s *runtime.Scheme) error {
+		s.AddKnownTypes(GroupVersion,
+			&ContentDiagnostic{},
+			&ContentDiagnosticList{},
+		)
+		metav1.AddToGroupVersion(s, GroupVersion)
+		return nil
+	})
+}
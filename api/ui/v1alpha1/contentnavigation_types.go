@@ -0,0 +1,72 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// ContentNavigationName is the name of the single ContentNavigation served per workspace.
+const ContentNavigationName = "default"
+
+// Node is a single navigation node as defined in a contentconfiguration.
+// +kubebuilder:pruning:PreserveUnknownFields
+// +kubebuilder:validation:Type=object
+type Node struct {
+	runtime.RawExtension `json:",inline"`
+}
+
+// ContentNavigationSource references a contentconfiguration the navigation was compiled from.
+type ContentNavigationSource struct {
+	// Name is the metadata.name of the contentconfiguration.
+	Name string `json:"name"`
+
+	// Cluster is the logical cluster the contentconfiguration was read from.
+	Cluster string `json:"cluster,omitempty"`
+}
+
+// ContentNavigationSpec defines the desired state of ContentNavigation.
+type ContentNavigationSpec struct {
+
+	// Nodes are the navigation nodes of all contentconfigurations, de-duplicated
+	// and sorted by their order.
+	Nodes []Node `json:"nodes,omitempty"`
+
+	// Sources are the contentconfigurations the nodes were compiled from.
+	Sources []ContentNavigationSource `json:"sources,omitempty"`
+}
+
+// ContentNavigationStatus defines the observed state of ContentNavigation.
+type ContentNavigationStatus struct {
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+
+// ContentNavigation is the Schema for the contentnavigations API.
+type ContentNavigation struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ContentNavigationSpec   `json:"spec,omitempty"`
+	Status ContentNavigationStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ContentNavigationList contains a list of ContentNavigation.
+type ContentNavigationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ContentNavigation `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(func(s *runtime.Scheme) error {
+		s.AddKnownTypes(GroupVersion,
+			&ContentNavigation{},
+			&ContentNavigationList{},
+		)
+		metav1.AddToGroupVersion(s, GroupVersion)
+		return nil
+	})
+}
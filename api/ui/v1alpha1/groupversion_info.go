@@ -0,0 +1,21 @@
+// Package v1alpha1 contains API Schema definitions for the ui v1alpha1 API group
+// served by the contentconfigurations virtual workspace.
+// +kubebuilder:object:generate=true
+// +groupName=ui.platform-mesh.io
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	// GroupVersion is group version used to register these objects.
+	GroupVersion = schema.GroupVersion{Group: "ui.platform-mesh.io", Version: "v1alpha1"}
+
+	// SchemeBuilder is used to add go types to the GroupVersionKind scheme.
+	SchemeBuilder = &runtime.SchemeBuilder{}
+
+	// AddToScheme adds the types in this group-version to the given scheme.
+	AddToScheme = SchemeBuilder.AddToScheme
+)
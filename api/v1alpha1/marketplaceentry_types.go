@@ -14,6 +14,11 @@ type MarketplaceEntrySpec struct {
 	// Empty means not installed.
 	APIBindingName string `json:"apiBindingName,omitempty"`
 
+	// Installable is true if the caller may install the entry into the
+	// workspace, i.e. create APIBindings there and bind the APIExport.
+	// +optional
+	Installable *bool `json:"installable,omitempty"`
+
 	// ProviderMetadata contains metadata about the provider of the marketplace entry.
 	ProviderMetadata extensionapiv1alpha1.ProviderMetadata `json:"providerMetadata"`
 
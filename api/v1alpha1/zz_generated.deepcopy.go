@@ -70,6 +70,11 @@ func (in *MarketplaceEntryList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MarketplaceEntrySpec) DeepCopyInto(out *MarketplaceEntrySpec) {
 	*out = *in
+	if in.Installable != nil {
+		in, out := &in.Installable, &out.Installable
+		*out = new(bool)
+		**out = **in
+	}
 	in.ProviderMetadata.DeepCopyInto(&out.ProviderMetadata)
 	in.APIExport.DeepCopyInto(&out.APIExport)
 }
@@ -6,6 +6,7 @@ import (
 	"github.com/platform-mesh/virtual-workspaces/pkg/config"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	genericapiserveroptions "k8s.io/apiserver/pkg/server/options"
 	"k8s.io/klog/v2"
 )
@@ -14,11 +15,15 @@ var (
 	cfg                            = config.NewServiceConfig()
 	secureServing                  = genericapiserveroptions.SecureServingOptions{}
 	delegatingAuthenticationOption = genericapiserveroptions.DelegatingAuthenticationOptions{}
+	auditOptions                   = genericapiserveroptions.NewAuditOptions()
 )
 
 var rootCmd = &cobra.Command{
 	Use:   "virtual-workspaces",
 	Short: "The Platform-Mesh virtual workspace",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return utilerrors.NewAggregate(cfg.Validate())
+	},
 }
 
 func init() {
@@ -29,6 +34,7 @@ func init() {
 
 	delegatingAuthenticationOption.AddFlags(startCmd.Flags())
 	secureServing.AddFlags(startCmd.Flags())
+	auditOptions.AddFlags(startCmd.Flags())
 
 	klogFlagSet := flag.NewFlagSet("klog", flag.ExitOnError)
 	klog.InitFlags(klogFlagSet)
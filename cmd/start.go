@@ -1,14 +1,19 @@
 package cmd
 
 import (
+	"fmt"
+
 	"github.com/kcp-dev/client-go/dynamic"
+	kcpkubernetesclientset "github.com/kcp-dev/client-go/kubernetes"
 	"github.com/kcp-dev/multicluster-provider/apiexport"
 	"github.com/kcp-dev/virtual-workspace-framework/pkg/authorization"
 	"github.com/spf13/cobra"
 
 	"github.com/platform-mesh/virtual-workspaces/pkg/authentication"
+	"github.com/platform-mesh/virtual-workspaces/pkg/config"
 	"github.com/platform-mesh/virtual-workspaces/pkg/contentconfiguration"
 	"github.com/platform-mesh/virtual-workspaces/pkg/marketplace"
+	"github.com/platform-mesh/virtual-workspaces/pkg/storage"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
@@ -17,6 +22,7 @@ import (
 	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
 
+	"k8s.io/apiserver/pkg/authentication/authenticator"
 	"k8s.io/apiserver/pkg/authentication/request/union"
 	genericapiserver "k8s.io/apiserver/pkg/server"
 
@@ -29,6 +35,7 @@ import (
 
 	kcpclientset "github.com/kcp-dev/sdk/client/clientset/versioned/cluster"
 	virtualrootapiserver "github.com/kcp-dev/virtual-workspace-framework/pkg/rootapiserver"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 )
 
@@ -60,7 +67,7 @@ var startCmd = &cobra.Command{
 			clientCfg.Host = cfg.ServerURL
 		}
 
-		clientCfg.QPS = -1 // Disable rate limiting for the client
+		clientCfg.QPS = -1 // Disable rate limiting for the client, requests are limited per tenant by storage.TenantLimiter
 
 		dynamicClient, err := dynamic.NewForConfig(clientCfg)
 		if err != nil {
@@ -72,6 +79,11 @@ var startCmd = &cobra.Command{
 			return err
 		}
 
+		kubeClusterClient, err := kcpkubernetesclientset.NewForConfig(clientCfg)
+		if err != nil {
+			return err
+		}
+
 		recommendedConfig := genericapiserver.NewRecommendedConfig(codecs)
 
 		err = secureServing.ApplyTo(&recommendedConfig.SecureServing)
@@ -84,6 +96,14 @@ var startCmd = &cobra.Command{
 			return err
 		}
 
+		if errs := auditOptions.Validate(); len(errs) > 0 {
+			return utilerrors.NewAggregate(errs)
+		}
+		err = auditOptions.ApplyTo(&recommendedConfig.Config)
+		if err != nil {
+			return err
+		}
+
 		rootAPIServerConfig, err := virtualrootapiserver.NewConfig(recommendedConfig)
 		if err != nil {
 			return err
@@ -104,15 +124,61 @@ var startCmd = &cobra.Command{
 			}
 		}()
 
+		var contentCache storage.ContentConfigurationCache
+		if cfg.ContentConfigurationAPIExportEndpointSliceName != "" {
+			contentProviderCfg := storage.WithContentConfigurationSelector(providerCfg, storage.ContentConfigurationCacheSelector(cfg))
+			contentProvider, err := apiexport.New(contentProviderCfg, cfg.ContentConfigurationAPIExportEndpointSliceName, apiexport.Options{
+				Scheme: scheme,
+			})
+			if err != nil {
+				return err
+			}
+
+			go func() {
+				if err := contentProvider.Start(ctx, nil); err != nil {
+					klog.ErrorS(err, "contentconfiguration apiexport provider stopped with error")
+				}
+			}()
+
+			contentCache = storage.NewContentConfigurationCache(contentProvider)
+		}
+
+		userClient := storage.ImpersonatingClusterClient(clientCfg)
+
 		rootAPIServerConfig.Extra.VirtualWorkspaces = []virtualrootapiserver.NamedVirtualWorkspace{
-			contentconfiguration.BuildVirtualWorkspace(ctx, cfg, dynamicClient, clusterClient, contentconfiguration.VirtualWorkspaceBaseURL()),
-			marketplace.BuildVirtualWorkspace(ctx, cfg, dynamicClient, clusterClient, marketplace.VirtualWorkspaceBaseURL(), marketplaceProvider),
+			contentconfiguration.BuildVirtualWorkspace(ctx, cfg, dynamicClient, userClient, clusterClient, kubeClusterClient, contentconfiguration.VirtualWorkspaceBaseURL(), contentCache),
+			marketplace.BuildVirtualWorkspace(ctx, cfg, dynamicClient, userClient, clusterClient, kubeClusterClient, marketplace.VirtualWorkspaceBaseURL(), marketplaceProvider),
+		}
+		if cfg.EnableAnonymousMarketplaceCatalog {
+			rootAPIServerConfig.Extra.VirtualWorkspaces = append(rootAPIServerConfig.Extra.VirtualWorkspaces,
+				marketplace.BuildCatalogVirtualWorkspace(ctx, cfg, marketplace.CatalogVirtualWorkspaceBaseURL(), marketplaceProvider),
+			)
 		}
 
-		rootAPIServerConfig.Generic.Authentication.Authenticator = union.New(
-			authentication.New(clientCfg),
+		var tokenAuthenticator authenticator.Request
+		switch cfg.AuthenticationMode {
+		case config.AuthenticationModeKCP:
+			tokenAuthenticator, err = authentication.New(clientCfg, cfg)
+			if err != nil {
+				return err
+			}
+		case config.AuthenticationModeJWT:
+			tokenAuthenticator, err = authentication.NewJWT(ctx, cfg.OIDCIssuersFile)
+			if err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unknown authentication mode %q", cfg.AuthenticationMode)
+		}
+
+		authenticators := []authenticator.Request{
+			tokenAuthenticator,
 			rootAPIServerConfig.Generic.Authentication.Authenticator,
-		)
+		}
+		if cfg.EnableAnonymousMarketplaceCatalog {
+			authenticators = append(authenticators, authentication.AnonymousFor(marketplace.CatalogName))
+		}
+		rootAPIServerConfig.Generic.Authentication.Authenticator = union.New(authenticators...)
 
 		rootAPIServerConfig.Generic.Authorization.Authorizer = authorization.NewVirtualWorkspaceAuthorizer(func() []virtualrootapiserver.NamedVirtualWorkspace {
 			return rootAPIServerConfig.Extra.VirtualWorkspaces
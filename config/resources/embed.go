@@ -4,3 +4,9 @@ import _ "embed"
 
 //go:embed apiresourceschema-marketplaceentries.marketplace.platform-mesh.io.yaml
 var ResourceSchema string
+
+//go:embed apiresourceschema-contentnavigations.ui.platform-mesh.io.yaml
+var NavigationResourceSchema string
+
+//go:embed apiresourceschema-contentdiagnostics.ui.platform-mesh.io.yaml
+var DiagnosticsResourceSchema string
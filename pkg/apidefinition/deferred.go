@@ -0,0 +1,62 @@
+package apidefinition
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	kcpapidefinition "github.com/kcp-dev/virtual-workspace-framework/pkg/dynamic/apidefinition"
+	dynamiccontext "github.com/kcp-dev/virtual-workspace-framework/pkg/dynamic/context"
+)
+
+type deferredState struct {
+	getter kcpapidefinition.APIDefinitionSetGetter
+	err    error
+}
+
+// DeferredProvider serves no APIs until its delegate is set, so a virtual
+// workspace can start while its APIs are still being resolved.
+type DeferredProvider struct {
+	state atomic.Pointer[deferredState]
+}
+
+// NewDeferredProvider returns a provider that is not ready until Set is called.
+func NewDeferredProvider() *DeferredProvider {
+	p := &DeferredProvider{}
+	p.state.Store(&deferredState{err: errors.New("apis are not resolved yet")})
+	return p
+}
+
+// Set makes the provider serve the APIs of getter.
+func (p *DeferredProvider) Set(getter kcpapidefinition.APIDefinitionSetGetter) {
+	p.state.Store(&deferredState{getter: getter})
+}
+
+// SetError records why the APIs could not be resolved yet. It is ignored once
+// a delegate is set.
+func (p *DeferredProvider) SetError(err error) {
+	current := p.state.Load()
+	if current.getter != nil {
+		return
+	}
+	p.state.CompareAndSwap(current, &deferredState{err: err})
+}
+
+// Ready returns nil once a delegate is set, or the reason it is not set yet.
+func (p *DeferredProvider) Ready() error {
+	state := p.state.Load()
+	if state.getter != nil {
+		return nil
+	}
+	return state.err
+}
+
+func (p *DeferredProvider) GetAPIDefinitionSet(ctx context.Context, key dynamiccontext.APIDomainKey) (apis kcpapidefinition.APIDefinitionSet, apisExist bool, err error) {
+	state := p.state.Load()
+	if state.getter == nil {
+		return nil, false, nil
+	}
+	return state.getter.GetAPIDefinitionSet(ctx, key)
+}
+
+var _ kcpapidefinition.APIDefinitionSetGetter = &DeferredProvider{}
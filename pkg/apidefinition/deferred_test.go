@@ -0,0 +1,47 @@
+package apidefinition
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	kcpapidefinition "github.com/kcp-dev/virtual-workspace-framework/pkg/dynamic/apidefinition"
+	dynamiccontext "github.com/kcp-dev/virtual-workspace-framework/pkg/dynamic/context"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type fakeAPIDefinitionSetGetter kcpapidefinition.APIDefinitionSet
+
+func (f fakeAPIDefinitionSetGetter) GetAPIDefinitionSet(context.Context, dynamiccontext.APIDomainKey) (kcpapidefinition.APIDefinitionSet, bool, error) {
+	return kcpapidefinition.APIDefinitionSet(f), len(f) > 0, nil
+}
+
+func TestDeferredProvider(t *testing.T) {
+	t.Parallel()
+
+	p := NewDeferredProvider()
+	require.Error(t, p.Ready())
+
+	apis, exist, err := p.GetAPIDefinitionSet(context.Background(), "")
+	require.NoError(t, err)
+	require.False(t, exist)
+	require.Nil(t, apis)
+
+	p.SetError(errors.New("apiresourceschema not found"))
+	require.EqualError(t, p.Ready(), "apiresourceschema not found")
+
+	gvr := schema.GroupVersionResource{Group: "ui.platform-mesh.io", Version: "v1alpha1", Resource: "contentconfigurations"}
+	p.Set(fakeAPIDefinitionSetGetter{gvr: nil})
+	require.NoError(t, p.Ready())
+
+	apis, exist, err = p.GetAPIDefinitionSet(context.Background(), "")
+	require.NoError(t, err)
+	require.True(t, exist)
+	require.Contains(t, apis, gvr)
+
+	// errors after the apis were resolved do not make the provider unready
+	p.SetError(errors.New("late failure"))
+	require.NoError(t, p.Ready())
+}
@@ -0,0 +1,141 @@
+package apidefinition
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	apisv1alpha1 "github.com/kcp-dev/sdk/apis/apis/v1alpha1"
+	kcpapidefinition "github.com/kcp-dev/virtual-workspace-framework/pkg/dynamic/apidefinition"
+	"github.com/kcp-dev/virtual-workspace-framework/pkg/dynamic/apiserver"
+	dynamiccontext "github.com/kcp-dev/virtual-workspace-framework/pkg/dynamic/context"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	genericapiserver "k8s.io/apiserver/pkg/server"
+)
+
+// Resource describes one of the resources served by a multi resource provider.
+// All served versions of the schema returned by Schema are served.
+type Resource struct {
+	Schema          func() *apisv1alpha1.APIResourceSchema
+	StorageProvider StorageProviderFunc
+}
+
+// StaticSchema returns a Resource schema func always serving the given schema.
+func StaticSchema(resourceSchema *apisv1alpha1.APIResourceSchema) func() *apisv1alpha1.APIResourceSchema {
+	return func() *apisv1alpha1.APIResourceSchema { return resourceSchema }
+}
+
+// MultiResourceProvider serves several resources side by side in the same
+// virtual workspace. The definition set is built once and only rebuilt on
+// Rebuild, e.g. when a schema changed. Rebuilds swap the set atomically and
+// tear the previous one down afterwards.
+type MultiResourceProvider struct {
+	ctx       context.Context
+	config    genericapiserver.CompletedConfig
+	resources []Resource
+
+	rebuildLock sync.Mutex
+	apis        atomic.Pointer[kcpapidefinition.APIDefinitionSet]
+	// cancel stops the storages of the served set.
+	cancel context.CancelFunc
+}
+
+// NewMultiResourceProvider returns a provider serving all given resources. The
+// storages are bound to a child of ctx per definition set, which is canceled
+// when the set is replaced.
+func NewMultiResourceProvider(
+	ctx context.Context,
+	config genericapiserver.CompletedConfig,
+	resources ...Resource,
+) (*MultiResourceProvider, error) {
+	p := &MultiResourceProvider{
+		ctx:       ctx,
+		config:    config,
+		resources: resources,
+	}
+
+	if err := p.Rebuild(); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Rebuild builds the definition set from the current schemas and replaces the
+// served one, tearing down the previous set and stopping its storages. On
+// errors the previous set keeps being served.
+func (p *MultiResourceProvider) Rebuild() error {
+	p.rebuildLock.Lock()
+	defer p.rebuildLock.Unlock()
+
+	ctx, cancel := context.WithCancel(p.ctx)
+	apis, err := p.build(ctx)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	previous := p.apis.Swap(&apis)
+	if previous != nil {
+		for _, apiDefinition := range *previous {
+			apiDefinition.TearDown()
+		}
+	}
+	if p.cancel != nil {
+		p.cancel()
+	}
+	p.cancel = cancel
+
+	return nil
+}
+
+func (p *MultiResourceProvider) build(ctx context.Context) (kcpapidefinition.APIDefinitionSet, error) {
+	apis := kcpapidefinition.APIDefinitionSet{}
+
+	for _, resource := range p.resources {
+		resourceSchema := resource.Schema()
+		if resourceSchema == nil {
+			return nil, fmt.Errorf("no schema available")
+		}
+
+		restProvider, err := resource.StorageProvider(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, version := range resourceSchema.Spec.Versions {
+			if !version.Served {
+				continue
+			}
+
+			gvr := schema.GroupVersionResource{
+				Group:    resourceSchema.Spec.Group,
+				Version:  version.Name,
+				Resource: resourceSchema.Spec.Names.Plural,
+			}
+
+			apiDefinition, err := apiserver.CreateServingInfoFor(
+				p.config,
+				resourceSchema,
+				version.Name,
+				restProvider,
+			)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create serving info for %s: %w", gvr, err)
+			}
+
+			apis[gvr] = apiDefinition
+		}
+	}
+
+	return apis, nil
+}
+
+func (p *MultiResourceProvider) GetAPIDefinitionSet(_ context.Context, _ dynamiccontext.APIDomainKey) (apis kcpapidefinition.APIDefinitionSet, apisExist bool, err error) {
+	apis = *p.apis.Load()
+	return apis, len(apis) > 0, nil
+}
+
+var _ kcpapidefinition.APIDefinitionSetGetter = &MultiResourceProvider{}
@@ -0,0 +1,50 @@
+package apidefinition
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	apisv1alpha1 "github.com/kcp-dev/sdk/apis/apis/v1alpha1"
+	"github.com/kcp-dev/virtual-workspace-framework/pkg/dynamic/apiserver"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	genericapiserver "k8s.io/apiserver/pkg/server"
+)
+
+func TestMultiResourceProvider_RebuildStopsPreviousStorages(t *testing.T) {
+	t.Parallel()
+
+	var (
+		storageCtxs []context.Context
+		failing     bool
+	)
+	resource := Resource{
+		// no served versions, so no serving info has to be created
+		Schema: StaticSchema(&apisv1alpha1.APIResourceSchema{}),
+		StorageProvider: func(ctx context.Context) (apiserver.RestProviderFunc, error) {
+			storageCtxs = append(storageCtxs, ctx)
+			if failing {
+				return nil, errors.New("storage unavailable")
+			}
+			return nil, nil
+		},
+	}
+
+	p, err := NewMultiResourceProvider(context.Background(), genericapiserver.CompletedConfig{}, resource)
+	require.NoError(t, err)
+	require.Len(t, storageCtxs, 1)
+
+	require.NoError(t, p.Rebuild())
+	require.Len(t, storageCtxs, 2)
+	assert.ErrorIs(t, storageCtxs[0].Err(), context.Canceled)
+	assert.NoError(t, storageCtxs[1].Err())
+
+	// a failed rebuild keeps serving the previous set
+	failing = true
+	require.Error(t, p.Rebuild())
+	require.Len(t, storageCtxs, 3)
+	assert.NoError(t, storageCtxs[1].Err())
+	assert.ErrorIs(t, storageCtxs[2].Err(), context.Canceled)
+}
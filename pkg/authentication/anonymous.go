@@ -0,0 +1,28 @@
+package authentication
+
+import (
+	"net/http"
+
+	virtualcontext "github.com/kcp-dev/virtual-workspace-framework/pkg/context"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/user"
+)
+
+// AnonymousFor authenticates requests to the named virtual workspace as the
+// anonymous user, if no other authenticator identified them. It has to be the
+// last authenticator of a union, requests to any other virtual workspace are
+// left unauthenticated.
+func AnonymousFor(virtualWorkspaceName string) authenticator.Request {
+	return authenticator.RequestFunc(func(req *http.Request) (*authenticator.Response, bool, error) {
+		if name, ok := virtualcontext.VirtualWorkspaceNameFrom(req.Context()); !ok || name != virtualWorkspaceName {
+			return nil, false, nil
+		}
+
+		return &authenticator.Response{
+			User: &user.DefaultInfo{
+				Name:   user.Anonymous,
+				Groups: []string{user.AllUnauthenticated},
+			},
+		}, true, nil
+	})
+}
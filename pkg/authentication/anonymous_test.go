@@ -0,0 +1,47 @@
+package authentication
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	virtualcontext "github.com/kcp-dev/virtual-workspace-framework/pkg/context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apiserver/pkg/authentication/user"
+)
+
+func TestAnonymousFor(t *testing.T) {
+	t.Parallel()
+
+	authn := AnonymousFor("marketplace-catalog")
+
+	tests := []struct {
+		name             string
+		virtualWorkspace string
+		expected         bool
+	}{
+		{name: "catalog requests are anonymous", virtualWorkspace: "marketplace-catalog", expected: true},
+		{name: "other virtual workspaces are left unauthenticated", virtualWorkspace: "marketplace", expected: false},
+		{name: "requests outside of virtual workspaces are left unauthenticated", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest("GET", "/apis", nil)
+			if tt.virtualWorkspace != "" {
+				req = req.WithContext(virtualcontext.WithVirtualWorkspaceName(req.Context(), tt.virtualWorkspace))
+			}
+
+			res, ok, err := authn.AuthenticateRequest(req)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, ok)
+			if ok {
+				assert.Equal(t, user.Anonymous, res.User.GetName())
+				assert.Equal(t, []string{user.AllUnauthenticated}, res.User.GetGroups())
+			}
+		})
+	}
+}
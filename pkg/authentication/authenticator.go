@@ -1,19 +1,36 @@
 package authentication
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
+	"slices"
+	"time"
 
+	"github.com/kcp-dev/logicalcluster/v3"
+	"github.com/platform-mesh/virtual-workspaces/pkg/config"
 	"github.com/platform-mesh/virtual-workspaces/pkg/storage"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apiserver/pkg/authentication/authenticator"
 	"k8s.io/apiserver/pkg/authentication/request/bearertoken"
 	"k8s.io/apiserver/pkg/authentication/user"
 	"k8s.io/client-go/rest"
 )
 
-func New(restCfg *rest.Config) authenticator.Request {
+// UserResolver returns the user a bearer token belongs to, verifying the token
+// against the kcp workspace at clusterPath.
+type UserResolver func(ctx context.Context, clusterPath logicalcluster.Path, token string) (user.Info, error)
+
+// New returns an authenticator verifying bearer tokens against the kcp
+// workspace of the request. It fails if restCfg cannot be used to reach kcp.
+func New(restCfg *rest.Config, serviceCfg config.ServiceConfig) (authenticator.Request, error) {
 	cfg := rest.CopyConfig(restCfg)
 
 	// disable cert/key data so that we do not use client certs for authentication
@@ -22,56 +39,237 @@ func New(restCfg *rest.Config) authenticator.Request {
 
 	client, err := rest.HTTPClientFor(cfg)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("failed to create http client for token authentication: %w", err)
 	}
 
 	parsedURL, err := url.Parse(cfg.Host)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("invalid kcp host %q: %w", cfg.Host, err)
+	}
+	if parsedURL.Scheme == "" || parsedURL.Host == "" {
+		return nil, fmt.Errorf("invalid kcp host %q: scheme and host are required", cfg.Host)
 	}
+	baseURL := fmt.Sprintf("%s://%s", parsedURL.Scheme, parsedURL.Host)
 
-	return bearertoken.New(
-		OIDCAuthenticator(client, fmt.Sprintf("%s://%s", parsedURL.Scheme, parsedURL.Host)),
-	)
+	resolveUser := SelfSubjectReviewResolver(client, baseURL)
+	if serviceCfg.UserInfoEndpoint != "" {
+		resolveUser = UserInfoResolver(client, baseURL, &http.Client{Timeout: 10 * time.Second}, serviceCfg)
+	}
+
+	tokenAuthenticator := OIDCAuthenticator(resolveUser)
+	if serviceCfg.TokenCacheSize > 0 {
+		tokenAuthenticator = CachedAuthenticator(tokenAuthenticator, serviceCfg.TokenCacheSize, serviceCfg.TokenCacheSuccessTTL, serviceCfg.TokenCacheFailureTTL)
+	}
+
+	return bearertoken.New(tokenAuthenticator), nil
 }
 
-func OIDCAuthenticator(client *http.Client, baseURL string) authenticator.Token {
+func OIDCAuthenticator(resolveUser UserResolver) authenticator.Token {
 	return authenticator.TokenFunc(func(ctx context.Context, token string) (*authenticator.Response, bool, error) {
 		clusterPath, ok := storage.ClusterPathFrom(ctx)
 		if !ok {
 			return &authenticator.Response{}, false, fmt.Errorf("no cluster path in context")
 		}
 
-		requestURL := fmt.Sprintf("%s/clusters/%s/version", baseURL, clusterPath.String())
-
-		req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, http.NoBody)
+		u, err := resolveUser(ctx, clusterPath, token)
+		if errors.Is(err, errInvalidToken) {
+			return nil, false, nil
+		}
 		if err != nil {
-			return nil, false, err
+			return &authenticator.Response{}, false, err
 		}
 
-		req.Header.Set("Authorization", "Bearer "+token)
+		return &authenticator.Response{User: withAuthenticatedGroup(u)}, true, nil
+	})
+}
+
+// errInvalidToken is returned by a UserResolver if the token was rejected.
+// Unlike other errors, this is an answer about the token and can be cached.
+var errInvalidToken = errors.New("invalid token")
+
+// unidentifiedUser is used for valid tokens whose user cannot be reviewed in
+// the requested workspace, e.g. because the user has no access to it. This is
+// similar to how the kube-apiserver handles authentication: a valid token
+// authenticates even if the user does not have permissions to do anything.
+// The hash of the token tells these callers apart, e.g. for rate limits.
+func unidentifiedUser(token string) user.Info {
+	hash := sha256.Sum256([]byte(token))
+	return &user.DefaultInfo{
+		Name:   user.Anonymous,
+		Groups: []string{user.AllAuthenticated},
+		Extra:  map[string][]string{storage.TokenHashExtraKey: {hex.EncodeToString(hash[:])}},
+	}
+}
 
-		res, err := client.Do(req)
+// SelfSubjectReviewResolver resolves the user with a SelfSubjectReview in the
+// requested workspace, which kcp answers with the identity of the token.
+func SelfSubjectReviewResolver(client *http.Client, baseURL string) UserResolver {
+	return func(ctx context.Context, clusterPath logicalcluster.Path, token string) (user.Info, error) {
+		requestURL := fmt.Sprintf("%s/clusters/%s/apis/authentication.k8s.io/v1/selfsubjectreviews", baseURL, clusterPath.String())
+
+		body, err := json.Marshal(&authenticationv1.SelfSubjectReview{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: authenticationv1.SchemeGroupVersion.String(),
+				Kind:       "SelfSubjectReview",
+			},
+		})
 		if err != nil {
-			return nil, false, err
+			return nil, err
 		}
-		defer res.Body.Close() //nolint:errcheck
 
-		switch res.StatusCode {
+		var review authenticationv1.SelfSubjectReview
+		status, err := doJSON(ctx, client, http.MethodPost, requestURL, token, body, &review)
+		if err != nil {
+			return nil, err
+		}
+
+		switch status {
+		case http.StatusOK, http.StatusCreated:
+			userInfo := review.Status.UserInfo
+			if userInfo.Username == "" {
+				return nil, fmt.Errorf("selfsubjectreview from %s has no username", requestURL)
+			}
+
+			extra := make(map[string][]string, len(userInfo.Extra))
+			for key, values := range userInfo.Extra {
+				extra[key] = values
+			}
+
+			return &user.DefaultInfo{
+				Name:   userInfo.Username,
+				UID:    userInfo.UID,
+				Groups: userInfo.Groups,
+				Extra:  extra,
+			}, nil
+		case http.StatusForbidden:
+			return unidentifiedUser(token), nil
+		case http.StatusUnauthorized:
+			return nil, errInvalidToken
+		default:
+			return nil, fmt.Errorf("unexpected status code %d from %s", status, requestURL)
+		}
+	}
+}
+
+// UserInfoResolver verifies the token against the requested workspace and
+// resolves the user from the claims returned by the configured OIDC userinfo
+// endpoint. userInfoClient is used for the endpoint, which is usually not
+// served by kcp.
+func UserInfoResolver(client *http.Client, baseURL string, userInfoClient *http.Client, cfg config.ServiceConfig) UserResolver {
+	return func(ctx context.Context, clusterPath logicalcluster.Path, token string) (user.Info, error) {
+		requestURL := fmt.Sprintf("%s/clusters/%s/version", baseURL, clusterPath.String())
+
+		status, err := doJSON(ctx, client, http.MethodGet, requestURL, token, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		switch status {
 		case http.StatusOK, http.StatusCreated, http.StatusForbidden:
-			// one could also continue here and use the OIDC userinfo endpoint to get more information about the user
-			// but for now, just having a valid token is enough to be considered authenticated
-			// even if the user does not have permissions to do anything (403)
-			// this is similar to how the kube-apiserver handles authentication
-			// we map all valid tokens to the "system:authenticated" group
-			return &authenticator.Response{
-				User: &user.DefaultInfo{
-					Name:   "system:anonymous",
-					Groups: []string{"system:authenticated"},
-				},
-			}, true, nil
+		case http.StatusUnauthorized:
+			return nil, errInvalidToken
 		default:
-			return &authenticator.Response{}, false, fmt.Errorf("unexpected status code %d from %s", res.StatusCode, requestURL)
+			return nil, fmt.Errorf("unexpected status code %d from %s", status, requestURL)
 		}
-	})
+
+		var claims map[string]any
+		status, err = doJSON(ctx, userInfoClient, http.MethodGet, cfg.UserInfoEndpoint, token, nil, &claims)
+		if err != nil {
+			return nil, err
+		}
+		switch status {
+		case http.StatusOK:
+		case http.StatusUnauthorized:
+			return nil, errInvalidToken
+		default:
+			return nil, fmt.Errorf("unexpected status code %d from %s", status, cfg.UserInfoEndpoint)
+		}
+
+		return userFromClaims(claims, claimMapping{
+			usernameClaim:  cfg.UserInfoUsernameClaim,
+			usernamePrefix: cfg.UserInfoUsernamePrefix,
+			groupsClaim:    cfg.UserInfoGroupsClaim,
+			groupsPrefix:   cfg.UserInfoGroupsPrefix,
+		})
+	}
+}
+
+// claimMapping selects the username and groups from OIDC claims. The prefixes
+// have to match those kcp uses for the same issuer, e.g. its
+// --oidc-username-prefix and --oidc-groups-prefix, for kcp to know the user.
+type claimMapping struct {
+	usernameClaim, usernamePrefix string
+	groupsClaim, groupsPrefix     string
+}
+
+// userFromClaims maps OIDC claims to a user. The subject is used as UID.
+func userFromClaims(claims map[string]any, mapping claimMapping) (user.Info, error) {
+	username, _ := claims[mapping.usernameClaim].(string)
+	if username == "" {
+		return nil, fmt.Errorf("claim %q is missing or not a string", mapping.usernameClaim)
+	}
+
+	u := &user.DefaultInfo{Name: mapping.usernamePrefix + username}
+	u.UID, _ = claims["sub"].(string)
+
+	switch groups := claims[mapping.groupsClaim].(type) {
+	case nil:
+	case string:
+		u.Groups = []string{mapping.groupsPrefix + groups}
+	case []any:
+		for _, group := range groups {
+			name, ok := group.(string)
+			if !ok {
+				return nil, fmt.Errorf("claim %q contains a non-string group", mapping.groupsClaim)
+			}
+			u.Groups = append(u.Groups, mapping.groupsPrefix+name)
+		}
+	default:
+		return nil, fmt.Errorf("claim %q is neither a string nor a list", mapping.groupsClaim)
+	}
+
+	return u, nil
+}
+
+// withAuthenticatedGroup adds system:authenticated, which the authorizers rely
+// on, if the identity source did not.
+func withAuthenticatedGroup(u user.Info) user.Info {
+	if slices.Contains(u.GetGroups(), user.AllAuthenticated) {
+		return u
+	}
+
+	return &user.DefaultInfo{
+		Name:   u.GetName(),
+		UID:    u.GetUID(),
+		Groups: append(slices.Clone(u.GetGroups()), user.AllAuthenticated),
+		Extra:  u.GetExtra(),
+	}
+}
+
+// doJSON sends a request authenticated with token and decodes a successful
+// response into out, if set. It returns the response status code.
+func doJSON(ctx context.Context, client *http.Client, method, requestURL, token string, body []byte, out any) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, requestURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close() //nolint:errcheck
+
+	if out != nil && res.StatusCode >= 200 && res.StatusCode < 300 {
+		if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+			return 0, fmt.Errorf("failed to decode response from %s: %w", requestURL, err)
+		}
+	}
+
+	return res.StatusCode, nil
 }
@@ -0,0 +1,232 @@
+package authentication
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+	"github.com/platform-mesh/virtual-workspaces/pkg/config"
+	"github.com/platform-mesh/virtual-workspaces/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/client-go/rest"
+)
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		restCfg   *rest.Config
+		expectErr string
+	}{
+		{name: "valid config", restCfg: &rest.Config{Host: "https://kcp.example.com:6443"}},
+		{
+			name:      "missing ca file",
+			restCfg:   &rest.Config{Host: "https://kcp.example.com:6443", TLSClientConfig: rest.TLSClientConfig{CAFile: "/does/not/exist"}},
+			expectErr: "failed to create http client",
+		},
+		{name: "unparsable host", restCfg: &rest.Config{Host: "https://kcp.example.com:port"}, expectErr: "invalid kcp host"},
+		{name: "host without scheme", restCfg: &rest.Config{Host: "kcp.example.com"}, expectErr: "scheme and host are required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			authn, err := New(tt.restCfg, config.NewServiceConfig())
+			if tt.expectErr != "" {
+				require.ErrorContains(t, err, tt.expectErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotNil(t, authn)
+		})
+	}
+}
+
+func TestSelfSubjectReviewResolver(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		status       int
+		expectedUser user.Info
+	}{
+		{
+			name:   "reviewed user",
+			status: http.StatusCreated,
+			expectedUser: &user.DefaultInfo{
+				Name:   "alice@example.com",
+				UID:    "1234",
+				Groups: []string{"admins", user.AllAuthenticated},
+				Extra:  map[string][]string{"scopes": {"openid"}},
+			},
+		},
+		{
+			name:         "valid token without access to the workspace",
+			status:       http.StatusForbidden,
+			expectedUser: unidentifiedUser("token"),
+		},
+		{
+			name:   "invalid token",
+			status: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodPost, r.Method)
+				assert.Equal(t, "/clusters/root:orgs:acme/apis/authentication.k8s.io/v1/selfsubjectreviews", r.URL.Path)
+				assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+
+				w.WriteHeader(tt.status)
+				_ = json.NewEncoder(w).Encode(&authenticationv1.SelfSubjectReview{
+					Status: authenticationv1.SelfSubjectReviewStatus{
+						UserInfo: authenticationv1.UserInfo{
+							Username: "alice@example.com",
+							UID:      "1234",
+							Groups:   []string{"admins"},
+							Extra:    map[string]authenticationv1.ExtraValue{"scopes": {"openid"}},
+						},
+					},
+				})
+			}))
+			t.Cleanup(server.Close)
+
+			authn := OIDCAuthenticator(SelfSubjectReviewResolver(server.Client(), server.URL))
+			ctx := storage.WithClusterPath(context.Background(), logicalcluster.NewPath("root:orgs:acme"))
+
+			res, ok, err := authn.AuthenticateToken(ctx, "token")
+			require.NoError(t, err)
+			if tt.expectedUser == nil {
+				assert.False(t, ok)
+				return
+			}
+			require.True(t, ok)
+			assert.Equal(t, tt.expectedUser, res.User)
+		})
+	}
+}
+
+func TestUserInfoResolver(t *testing.T) {
+	t.Parallel()
+
+	kcp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/clusters/root:orgs:acme/version", r.URL.Path)
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	t.Cleanup(kcp.Close)
+
+	idp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"sub":   "1234",
+			"email": "alice@example.com",
+			"groups": []string{
+				"admins",
+			},
+		})
+	}))
+	t.Cleanup(idp.Close)
+
+	tests := []struct {
+		name           string
+		usernamePrefix string
+		groupsPrefix   string
+		expectedUser   user.Info
+	}{
+		{
+			name: "claims as they are",
+			expectedUser: &user.DefaultInfo{
+				Name:   "alice@example.com",
+				UID:    "1234",
+				Groups: []string{"admins", user.AllAuthenticated},
+			},
+		},
+		{
+			name:           "prefixed like by kcp",
+			usernamePrefix: "oidc:",
+			groupsPrefix:   "oidc:",
+			expectedUser: &user.DefaultInfo{
+				Name:   "oidc:alice@example.com",
+				UID:    "1234",
+				Groups: []string{"oidc:admins", user.AllAuthenticated},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := config.NewServiceConfig()
+			cfg.UserInfoEndpoint = idp.URL
+			cfg.UserInfoUsernamePrefix = tt.usernamePrefix
+			cfg.UserInfoGroupsPrefix = tt.groupsPrefix
+
+			authn := OIDCAuthenticator(UserInfoResolver(kcp.Client(), kcp.URL, idp.Client(), cfg))
+			ctx := storage.WithClusterPath(context.Background(), logicalcluster.NewPath("root:orgs:acme"))
+
+			res, ok, err := authn.AuthenticateToken(ctx, "token")
+			require.NoError(t, err)
+			require.True(t, ok)
+			assert.Equal(t, tt.expectedUser, res.User)
+		})
+	}
+}
+
+func TestUserFromClaims(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		claims       map[string]any
+		expectedUser user.Info
+		expectErr    bool
+	}{
+		{
+			name:         "single group",
+			claims:       map[string]any{"email": "alice@example.com", "groups": "admins"},
+			expectedUser: &user.DefaultInfo{Name: "alice@example.com", Groups: []string{"admins"}},
+		},
+		{
+			name:         "no groups",
+			claims:       map[string]any{"email": "alice@example.com", "sub": "1234"},
+			expectedUser: &user.DefaultInfo{Name: "alice@example.com", UID: "1234"},
+		},
+		{
+			name:      "missing username",
+			claims:    map[string]any{"sub": "1234"},
+			expectErr: true,
+		},
+		{
+			name:      "invalid groups",
+			claims:    map[string]any{"email": "alice@example.com", "groups": []any{1}},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			u, err := userFromClaims(tt.claims, claimMapping{usernameClaim: "email", groupsClaim: "groups"})
+			if tt.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedUser, u)
+		})
+	}
+}
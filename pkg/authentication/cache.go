@@ -0,0 +1,98 @@
+package authentication
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/platform-mesh/virtual-workspaces/pkg/storage"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/utils/clock"
+	"k8s.io/utils/lru"
+)
+
+// tokenCacheResult is a cached result of the delegate authenticator.
+type tokenCacheResult struct {
+	response *authenticator.Response
+	ok       bool
+	expires  time.Time
+}
+
+// CachedAuthenticator caches the results of delegate per token and workspace,
+// as a token can be valid in one workspace and not in another. Successful
+// authentications are cached for successTTL, but never beyond the expiry of a
+// JWT, rejected tokens for failureTTL. Errors, e.g. a TokenReview timing out,
+// say nothing about the token and are never cached. At most size results are
+// kept.
+func CachedAuthenticator(delegate authenticator.Token, size int, successTTL, failureTTL time.Duration) authenticator.Token {
+	return cachedAuthenticator(delegate, size, successTTL, failureTTL, clock.RealClock{})
+}
+
+func cachedAuthenticator(delegate authenticator.Token, size int, successTTL, failureTTL time.Duration, clk clock.Clock) authenticator.Token {
+	results := lru.New(size)
+
+	return authenticator.TokenFunc(func(ctx context.Context, token string) (*authenticator.Response, bool, error) {
+		clusterPath, _ := storage.ClusterPathFrom(ctx)
+		key := tokenCacheKey(token, clusterPath.String())
+
+		if cached, ok := results.Get(key); ok {
+			result := cached.(*tokenCacheResult)
+			if clk.Now().Before(result.expires) {
+				tokenCacheRequests.WithLabelValues("hit", strconv.FormatBool(result.ok)).Inc()
+				return result.response, result.ok, nil
+			}
+			results.Remove(key)
+			tokenCacheEntries.Set(float64(results.Len()))
+		}
+
+		response, ok, err := delegate.AuthenticateToken(ctx, token)
+		tokenCacheRequests.WithLabelValues("miss", strconv.FormatBool(ok)).Inc()
+
+		// neither an error nor a canceled request say anything about the token
+		if err != nil || ctx.Err() != nil {
+			return response, ok, err
+		}
+
+		ttl := failureTTL
+		if ok {
+			ttl = successTTL
+			if expiry, hasExpiry := jwtExpiry(token); hasExpiry {
+				ttl = min(ttl, expiry.Sub(clk.Now()))
+			}
+		}
+		if ttl > 0 {
+			results.Add(key, &tokenCacheResult{response: response, ok: ok, expires: clk.Now().Add(ttl)})
+			tokenCacheEntries.Set(float64(results.Len()))
+		}
+
+		return response, ok, err
+	})
+}
+
+// tokenCacheKey keys results by a hash of the token, so the cache does not hold
+// usable credentials.
+func tokenCacheKey(token, clusterPath string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:]) + "/" + clusterPath
+}
+
+// jwtExpiry returns the exp claim if token is a JWT. The token is not verified,
+// the expiry only limits how long a result of the delegate is reused.
+func jwtExpiry(token string) (time.Time, bool) {
+	var claims struct {
+		Exp *json.Number `json:"exp"`
+	}
+	if !unverifiedClaims(token, &claims) || claims.Exp == nil {
+		return time.Time{}, false
+	}
+
+	exp, err := claims.Exp.Float64()
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return time.Unix(int64(exp), 0), true
+}
@@ -0,0 +1,113 @@
+package authentication
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+	"github.com/platform-mesh/virtual-workspaces/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/user"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func newJWT(t *testing.T, exp time.Time) string {
+	t.Helper()
+
+	payload := base64.RawURLEncoding.EncodeToString(fmt.Appendf(nil, `{"sub":"alice","exp":%d}`, exp.Unix()))
+	return "eyJhbGciOiJSUzI1NiJ9." + payload + ".signature"
+}
+
+func TestCachedAuthenticator(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+
+	tests := []struct {
+		name          string
+		token         string
+		fail          bool
+		errored       bool
+		elapsed       time.Duration
+		otherWS       bool
+		expectedCalls int
+	}{
+		{name: "success is reused within the success ttl", token: "opaque", elapsed: 50 * time.Second, expectedCalls: 1},
+		{name: "success expires after the success ttl", token: "opaque", elapsed: 61 * time.Second, expectedCalls: 2},
+		{name: "failure expires after the failure ttl", token: "opaque", fail: true, elapsed: 11 * time.Second, expectedCalls: 2},
+		{name: "failure is reused within the failure ttl", token: "opaque", fail: true, elapsed: 5 * time.Second, expectedCalls: 1},
+		{name: "errors are not cached", token: "opaque", fail: true, errored: true, elapsed: time.Second, expectedCalls: 2},
+		{name: "success expires with the jwt", token: newJWT(t, now.Add(20*time.Second)), elapsed: 21 * time.Second, expectedCalls: 2},
+		{name: "jwt before expiry is reused", token: newJWT(t, now.Add(20*time.Second)), elapsed: 19 * time.Second, expectedCalls: 1},
+		{name: "expired jwt is not cached", token: newJWT(t, now.Add(-time.Second)), expectedCalls: 2},
+		{name: "results are per workspace", token: "opaque", otherWS: true, expectedCalls: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			calls := 0
+			delegate := authenticator.TokenFunc(func(ctx context.Context, token string) (*authenticator.Response, bool, error) {
+				calls++
+				if tt.errored {
+					return nil, false, errors.New("tokenreview timed out")
+				}
+				if tt.fail {
+					return nil, false, nil
+				}
+				return &authenticator.Response{User: &user.DefaultInfo{Name: "alice"}}, true, nil
+			})
+
+			clk := clocktesting.NewFakeClock(now)
+			authn := cachedAuthenticator(delegate, 10, time.Minute, 10*time.Second, clk)
+
+			ctx := storage.WithClusterPath(context.Background(), logicalcluster.NewPath("root:orgs:acme"))
+			_, ok, err := authn.AuthenticateToken(ctx, tt.token)
+			assert.Equal(t, !tt.fail, ok)
+			assert.Equal(t, tt.errored, err != nil)
+
+			clk.Step(tt.elapsed)
+			if tt.otherWS {
+				ctx = storage.WithClusterPath(context.Background(), logicalcluster.NewPath("root:orgs:other"))
+			}
+			res, ok, err := authn.AuthenticateToken(ctx, tt.token)
+			assert.Equal(t, !tt.fail, ok)
+			switch {
+			case tt.errored:
+				require.Error(t, err)
+			case tt.fail:
+				require.NoError(t, err)
+				assert.Nil(t, res)
+			default:
+				require.NoError(t, err)
+				assert.Equal(t, "alice", res.User.GetName())
+			}
+
+			assert.Equal(t, tt.expectedCalls, calls)
+		})
+	}
+}
+
+func TestJWTExpiry(t *testing.T) {
+	t.Parallel()
+
+	exp := time.Unix(1893456000, 0)
+
+	got, ok := jwtExpiry(newJWT(t, exp))
+	require.True(t, ok)
+	assert.Equal(t, exp, got)
+
+	_, ok = jwtExpiry("opaque-token")
+	assert.False(t, ok)
+
+	_, ok = jwtExpiry("a.not-base64!.c")
+	assert.False(t, ok)
+}
@@ -0,0 +1,237 @@
+package authentication
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/go-jose/go-jose/v4"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/request/bearertoken"
+)
+
+// IssuerConfig configures an OIDC issuer whose tokens are validated locally.
+type IssuerConfig struct {
+	// URL is the issuer, it has to match the iss claim of its tokens.
+	URL string `json:"url"`
+	// Audiences lists the accepted aud claims, a token needs to match one of them.
+	Audiences []string `json:"audiences"`
+
+	// JWKSFile is a file holding the JSON Web Key Set the tokens are signed
+	// with. Either JWKSFile or JWKSURL is required.
+	JWKSFile string `json:"jwksFile,omitempty"`
+	JWKSURL  string `json:"jwksURL,omitempty"`
+
+	// UsernameClaim defaults to sub, GroupsClaim to groups. Like in
+	// kube-apiserver, UsernamePrefix defaults to "<url>#" for claims other than
+	// email, so the same subject of different issuers is not the same user;
+	// "-" disables the prefix.
+	UsernameClaim  string `json:"usernameClaim,omitempty"`
+	UsernamePrefix string `json:"usernamePrefix,omitempty"`
+	GroupsClaim    string `json:"groupsClaim,omitempty"`
+	GroupsPrefix   string `json:"groupsPrefix,omitempty"`
+}
+
+// IssuersConfig is the content of the file passed as --oidc-issuers-file.
+type IssuersConfig struct {
+	Issuers []IssuerConfig `json:"issuers"`
+}
+
+// NewJWT returns an authenticator validating bearer tokens against the issuers
+// configured in issuersFile, without calling kcp.
+func NewJWT(ctx context.Context, issuersFile string) (authenticator.Request, error) {
+	issuers, err := LoadIssuers(issuersFile)
+	if err != nil {
+		return nil, err
+	}
+
+	jwtAuthenticator, err := JWTAuthenticator(ctx, issuers)
+	if err != nil {
+		return nil, err
+	}
+
+	return bearertoken.New(jwtAuthenticator), nil
+}
+
+// LoadIssuers reads and validates an IssuersConfig from a YAML or JSON file.
+func LoadIssuers(path string) ([]IssuerConfig, error) {
+	if path == "" {
+		return nil, errors.New("no oidc issuers file configured")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read oidc issuers file: %w", err)
+	}
+
+	var cfg IssuersConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse oidc issuers file %s: %w", path, err)
+	}
+	if len(cfg.Issuers) == 0 {
+		return nil, fmt.Errorf("oidc issuers file %s configures no issuers", path)
+	}
+
+	for i, issuer := range cfg.Issuers {
+		switch {
+		case issuer.URL == "":
+			return nil, fmt.Errorf("issuer %d has no url", i)
+		case len(issuer.Audiences) == 0:
+			return nil, fmt.Errorf("issuer %s has no audiences", issuer.URL)
+		case (issuer.JWKSFile == "") == (issuer.JWKSURL == ""):
+			return nil, fmt.Errorf("issuer %s needs exactly one of jwksFile and jwksURL", issuer.URL)
+		}
+	}
+
+	return cfg.Issuers, nil
+}
+
+type issuerVerifier struct {
+	config   IssuerConfig
+	verifier *oidc.IDTokenVerifier
+}
+
+// JWTAuthenticator validates tokens of the given issuers: their signature,
+// issuer, audience and expiry. Tokens that are no JWTs or are issued by an
+// unknown issuer are left to the other authenticators.
+func JWTAuthenticator(ctx context.Context, issuers []IssuerConfig) (authenticator.Token, error) {
+	return jwtAuthenticator(ctx, issuers, time.Now)
+}
+
+func jwtAuthenticator(ctx context.Context, issuers []IssuerConfig, now func() time.Time) (authenticator.Token, error) {
+	verifiers := make(map[string]issuerVerifier, len(issuers))
+	for _, issuer := range issuers {
+		var keySet oidc.KeySet
+		if issuer.JWKSFile != "" {
+			fileKeySet, err := loadKeySet(issuer.JWKSFile)
+			if err != nil {
+				return nil, fmt.Errorf("issuer %s: %w", issuer.URL, err)
+			}
+			keySet = fileKeySet
+		} else {
+			keySet = oidc.NewRemoteKeySet(ctx, issuer.JWKSURL)
+		}
+
+		verifiers[issuer.URL] = issuerVerifier{
+			config: issuer,
+			verifier: oidc.NewVerifier(issuer.URL, keySet, &oidc.Config{
+				// the audience is checked against all configured ones below
+				SkipClientIDCheck: true,
+				Now:               now,
+			}),
+		}
+	}
+
+	return authenticator.TokenFunc(func(ctx context.Context, token string) (*authenticator.Response, bool, error) {
+		issuer, ok := unverifiedIssuer(token)
+		if !ok {
+			return nil, false, nil
+		}
+		v, ok := verifiers[issuer]
+		if !ok {
+			return nil, false, nil
+		}
+
+		idToken, err := v.verifier.Verify(ctx, token)
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid token from issuer %s: %w", issuer, err)
+		}
+		if !slices.ContainsFunc(idToken.Audience, func(aud string) bool {
+			return slices.Contains(v.config.Audiences, aud)
+		}) {
+			return nil, false, fmt.Errorf("token from issuer %s has none of the accepted audiences", issuer)
+		}
+
+		var claims map[string]any
+		if err := idToken.Claims(&claims); err != nil {
+			return nil, false, err
+		}
+
+		u, err := userFromClaims(claims, claimMapping{
+			usernameClaim:  cmp.Or(v.config.UsernameClaim, "sub"),
+			usernamePrefix: usernamePrefix(v.config),
+			groupsClaim:    cmp.Or(v.config.GroupsClaim, "groups"),
+			groupsPrefix:   v.config.GroupsPrefix,
+		})
+		if err != nil {
+			return nil, false, fmt.Errorf("token from issuer %s: %w", issuer, err)
+		}
+
+		return &authenticator.Response{User: withAuthenticatedGroup(u)}, true, nil
+	}), nil
+}
+
+// usernamePrefix returns the prefix of the usernames of issuer.
+func usernamePrefix(issuer IssuerConfig) string {
+	switch {
+	case issuer.UsernamePrefix == "-":
+		return ""
+	case issuer.UsernamePrefix == "" && cmp.Or(issuer.UsernameClaim, "sub") != "email":
+		return issuer.URL + "#"
+	}
+	return issuer.UsernamePrefix
+}
+
+// unverifiedIssuer returns the iss claim of a JWT, used to select the verifier.
+func unverifiedIssuer(token string) (string, bool) {
+	var claims struct {
+		Issuer string `json:"iss"`
+	}
+	if !unverifiedClaims(token, &claims) || claims.Issuer == "" {
+		return "", false
+	}
+
+	return claims.Issuer, true
+}
+
+// unverifiedClaims decodes the payload of a JWT into out without verifying it.
+// It returns false if token is no JWT.
+func unverifiedClaims(token string, out any) bool {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return false
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return false
+	}
+
+	return json.Unmarshal(payload, out) == nil
+}
+
+// loadKeySet reads a JSON Web Key Set and returns a key set verifying
+// signatures against its public keys.
+func loadKeySet(path string) (*oidc.StaticKeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read jwks file: %w", err)
+	}
+
+	var jwks jose.JSONWebKeySet
+	if err := json.Unmarshal(data, &jwks); err != nil {
+		return nil, fmt.Errorf("failed to parse jwks file %s: %w", path, err)
+	}
+	if len(jwks.Keys) == 0 {
+		return nil, fmt.Errorf("jwks file %s holds no keys", path)
+	}
+
+	keySet := &oidc.StaticKeySet{}
+	for _, key := range jwks.Keys {
+		if !key.IsPublic() {
+			return nil, fmt.Errorf("jwks file %s holds a private key", path)
+		}
+		keySet.PublicKeys = append(keySet.PublicKeys, key.Key)
+	}
+
+	return keySet, nil
+}
@@ -0,0 +1,221 @@
+package authentication
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apiserver/pkg/authentication/user"
+)
+
+func TestJWTAuthenticator(t *testing.T) {
+	t.Parallel()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	jwks, err := json.Marshal(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: key.Public(), KeyID: "key-1", Algorithm: string(jose.RS256), Use: "sig"},
+	}})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(jwksFile, jwks, 0o600))
+
+	now := time.Unix(1893456000, 0)
+	sign := func(t *testing.T, signingKey *rsa.PrivateKey, claims map[string]any) string {
+		t.Helper()
+
+		signer, err := jose.NewSigner(
+			jose.SigningKey{Algorithm: jose.RS256, Key: signingKey},
+			(&jose.SignerOptions{}).WithHeader("kid", "key-1"),
+		)
+		require.NoError(t, err)
+
+		payload, err := json.Marshal(claims)
+		require.NoError(t, err)
+		jws, err := signer.Sign(payload)
+		require.NoError(t, err)
+		token, err := jws.CompactSerialize()
+		require.NoError(t, err)
+		return token
+	}
+
+	validClaims := func() map[string]any {
+		return map[string]any{
+			"iss":    "https://idp.example.com",
+			"aud":    []string{"other", "platform-mesh"},
+			"sub":    "1234",
+			"email":  "alice@example.com",
+			"groups": []string{"admins"},
+			"exp":    now.Add(time.Hour).Unix(),
+		}
+	}
+
+	authn, err := jwtAuthenticator(context.Background(), []IssuerConfig{{
+		URL:            "https://idp.example.com",
+		Audiences:      []string{"platform-mesh"},
+		JWKSFile:       jwksFile,
+		UsernameClaim:  "email",
+		UsernamePrefix: "oidc:",
+		GroupsPrefix:   "oidc:",
+	}}, func() time.Time { return now })
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		token        func(t *testing.T) string
+		expectedUser user.Info
+		expectErr    bool
+	}{
+		{
+			name:  "valid token",
+			token: func(t *testing.T) string { return sign(t, key, validClaims()) },
+			expectedUser: &user.DefaultInfo{
+				Name:   "oidc:alice@example.com",
+				UID:    "1234",
+				Groups: []string{"oidc:admins", user.AllAuthenticated},
+			},
+		},
+		{
+			name: "expired token",
+			token: func(t *testing.T) string {
+				claims := validClaims()
+				claims["exp"] = now.Add(-time.Minute).Unix()
+				return sign(t, key, claims)
+			},
+			expectErr: true,
+		},
+		{
+			name: "wrong audience",
+			token: func(t *testing.T) string {
+				claims := validClaims()
+				claims["aud"] = "other"
+				return sign(t, key, claims)
+			},
+			expectErr: true,
+		},
+		{
+			name:      "unknown signing key",
+			token:     func(t *testing.T) string { return sign(t, otherKey, validClaims()) },
+			expectErr: true,
+		},
+		{
+			name: "missing username claim",
+			token: func(t *testing.T) string {
+				claims := validClaims()
+				delete(claims, "email")
+				return sign(t, key, claims)
+			},
+			expectErr: true,
+		},
+		{
+			name: "unknown issuer is left to other authenticators",
+			token: func(t *testing.T) string {
+				claims := validClaims()
+				claims["iss"] = "https://other.example.com"
+				return sign(t, key, claims)
+			},
+		},
+		{
+			name:  "opaque token is left to other authenticators",
+			token: func(t *testing.T) string { return "opaque" },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			res, ok, err := authn.AuthenticateToken(context.Background(), tt.token(t))
+			if tt.expectErr {
+				require.Error(t, err)
+				assert.False(t, ok)
+				return
+			}
+			require.NoError(t, err)
+			if tt.expectedUser == nil {
+				assert.False(t, ok)
+				return
+			}
+			require.True(t, ok)
+			assert.Equal(t, tt.expectedUser, res.User)
+		})
+	}
+}
+
+func TestUsernamePrefix(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		issuer   IssuerConfig
+		expected string
+	}{
+		{name: "subjects are prefixed with the issuer", issuer: IssuerConfig{URL: "https://idp.example.com"}, expected: "https://idp.example.com#"},
+		{name: "other claims are prefixed with the issuer", issuer: IssuerConfig{URL: "https://idp.example.com", UsernameClaim: "preferred_username"}, expected: "https://idp.example.com#"},
+		{name: "emails are not prefixed", issuer: IssuerConfig{URL: "https://idp.example.com", UsernameClaim: "email"}},
+		{name: "explicit prefix", issuer: IssuerConfig{URL: "https://idp.example.com", UsernamePrefix: "oidc:"}, expected: "oidc:"},
+		{name: "disabled prefix", issuer: IssuerConfig{URL: "https://idp.example.com", UsernamePrefix: "-"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.expected, usernamePrefix(tt.issuer))
+		})
+	}
+}
+
+func TestLoadIssuers(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		content   string
+		expectErr bool
+	}{
+		{
+			name: "valid issuers",
+			content: `issuers:
+- url: https://idp.example.com
+  audiences: [platform-mesh]
+  jwksFile: /etc/jwks.json
+- url: https://other.example.com
+  audiences: [platform-mesh]
+  jwksURL: https://other.example.com/keys
+`,
+		},
+		{name: "no issuers", content: "issuers: []", expectErr: true},
+		{name: "no audiences", content: "issuers:\n- url: https://idp.example.com\n  jwksFile: /etc/jwks.json", expectErr: true},
+		{name: "no jwks", content: "issuers:\n- url: https://idp.example.com\n  audiences: [a]", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "issuers.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0o600))
+
+			issuers, err := LoadIssuers(path)
+			if tt.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, issuers, 2)
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package authentication
+
+import (
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+const metricsSubsystem = "token_authentication"
+
+var tokenCacheRequests = metrics.NewCounterVec(
+	&metrics.CounterOpts{
+		Subsystem:      metricsSubsystem,
+		Name:           "cache_requests_total",
+		Help:           "Number of token authentications served by the token cache, by result (hit or miss) and whether the token was authenticated.",
+		StabilityLevel: metrics.ALPHA,
+	},
+	[]string{"result", "authenticated"},
+)
+
+var tokenCacheEntries = metrics.NewGauge(
+	&metrics.GaugeOpts{
+		Subsystem:      metricsSubsystem,
+		Name:           "cache_entries",
+		Help:           "Number of token authentication results in the token cache, including expired ones not evicted yet.",
+		StabilityLevel: metrics.ALPHA,
+	},
+)
+
+func init() {
+	legacyregistry.MustRegister(tokenCacheRequests, tokenCacheEntries)
+}
@@ -2,7 +2,9 @@ package authorization
 
 import (
 	"context"
+	"errors"
 
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apiserver/pkg/authentication/user"
 	"k8s.io/apiserver/pkg/authorization/authorizer"
 )
@@ -24,13 +26,19 @@ func WithAttributeHolder(parent context.Context) context.Context {
 	return context.WithValue(parent, attributeHolderCtxKey, &attributeHolder{})
 }
 
-func AttributesFromContext(ctx context.Context) authorizer.Attributes {
+// AttributesFromContext returns the attributes the request was authorized
+// with. It fails with an internal error if the path resolver did not set up a
+// holder or the request was not authorized yet.
+func AttributesFromContext(ctx context.Context) (authorizer.Attributes, error) {
 	holder, ok := ctx.Value(attributeHolderCtxKey).(*attributeHolder)
 	if !ok {
-		panic("Expected auth attributes to be present in context, but is not.")
+		return nil, kerrors.NewInternalError(errors.New("authorization attributes holder missing from request context"))
+	}
+	if holder.Attributes == nil {
+		return nil, kerrors.NewInternalError(errors.New("authorization attributes missing from request context"))
 	}
 
-	return holder.Attributes
+	return holder.Attributes, nil
 }
 
 func UpdateAttributes(ctx context.Context, attributes authorizer.Attributes) {
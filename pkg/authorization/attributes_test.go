@@ -0,0 +1,45 @@
+package authorization
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+)
+
+func TestAttributesFromContext(t *testing.T) {
+	t.Parallel()
+
+	t.Run("returns the authorized attributes", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := WithAttributeHolder(context.Background())
+		UpdateAttributes(ctx, authorizer.AttributesRecord{User: &user.DefaultInfo{Name: "alice"}, Verb: "list"})
+
+		attributes, err := AttributesFromContext(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "alice", attributes.GetUser().GetName())
+	})
+
+	t.Run("fails without a holder", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := AttributesFromContext(context.Background())
+		require.Error(t, err)
+		assert.True(t, kerrors.IsInternalError(err))
+		assert.Contains(t, err.Error(), "holder missing")
+	})
+
+	t.Run("fails before the request is authorized", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := AttributesFromContext(WithAttributeHolder(context.Background()))
+		require.Error(t, err)
+		assert.True(t, kerrors.IsInternalError(err))
+	})
+}
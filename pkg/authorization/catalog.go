@@ -0,0 +1,26 @@
+package authorization
+
+import (
+	"context"
+
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+)
+
+// NewCatalogAuthorizer allows every user, including anonymous ones, to get
+// and list the given resource and to read discovery. Everything else is denied,
+// so a catalog virtual workspace stays read-only whatever it serves.
+func NewCatalogAuthorizer(group, resource string) authorizer.Authorizer {
+	return authorizer.AuthorizerFunc(func(ctx context.Context, a authorizer.Attributes) (authorizer.Decision, string, error) {
+		if !a.IsReadOnly() {
+			return authorizer.DecisionDeny, "the catalog is read-only", nil
+		}
+		if !a.IsResourceRequest() {
+			return authorizer.DecisionAllow, "discovery is public", nil
+		}
+		if a.GetAPIGroup() != group || a.GetResource() != resource || a.GetSubresource() != "" || a.GetVerb() == "watch" {
+			return authorizer.DecisionDeny, "only get and list of " + resource + " are served", nil
+		}
+
+		return authorizer.DecisionAllow, "the catalog is public", nil
+	})
+}
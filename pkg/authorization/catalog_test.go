@@ -0,0 +1,53 @@
+package authorization
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+)
+
+func TestCatalogAuthorizer(t *testing.T) {
+	t.Parallel()
+
+	anonymous := &user.DefaultInfo{Name: user.Anonymous, Groups: []string{user.AllUnauthenticated}}
+	authz := NewCatalogAuthorizer("marketplace.platform-mesh.io", "marketplaceentries")
+
+	resourceRequest := func(verb, group, resource string) authorizer.Attributes {
+		return authorizer.AttributesRecord{
+			User:            anonymous,
+			Verb:            verb,
+			APIGroup:        group,
+			Resource:        resource,
+			ResourceRequest: true,
+		}
+	}
+
+	tests := []struct {
+		name     string
+		attr     authorizer.Attributes
+		expected authorizer.Decision
+	}{
+		{name: "list entries", attr: resourceRequest("list", "marketplace.platform-mesh.io", "marketplaceentries"), expected: authorizer.DecisionAllow},
+		{name: "get entry", attr: resourceRequest("get", "marketplace.platform-mesh.io", "marketplaceentries"), expected: authorizer.DecisionAllow},
+		{name: "watch entries", attr: resourceRequest("watch", "marketplace.platform-mesh.io", "marketplaceentries"), expected: authorizer.DecisionDeny},
+		{name: "create entry", attr: resourceRequest("create", "marketplace.platform-mesh.io", "marketplaceentries"), expected: authorizer.DecisionDeny},
+		{name: "other resource", attr: resourceRequest("list", "apis.kcp.io", "apibindings"), expected: authorizer.DecisionDeny},
+		{name: "discovery", attr: authorizer.AttributesRecord{User: anonymous, Verb: "get", Path: "/apis"}, expected: authorizer.DecisionAllow},
+		{name: "non-resource write", attr: authorizer.AttributesRecord{User: anonymous, Verb: "post", Path: "/apis"}, expected: authorizer.DecisionDeny},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			decision, _, err := authz.Authorize(context.Background(), tt.attr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, decision)
+		})
+	}
+}
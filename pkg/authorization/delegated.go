@@ -0,0 +1,102 @@
+package authorization
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	kcpkubernetesclientset "github.com/kcp-dev/client-go/kubernetes"
+	"github.com/kcp-dev/kcp/pkg/authorization/delegated"
+
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+)
+
+// EquivalentFunc maps the attributes of a virtual workspace request to the
+// request on the underlying kcp resource that is authorized instead.
+type EquivalentFunc func(a authorizer.Attributes) authorizer.Attributes
+
+// RemapResource authorizes every request with its verb on the given
+// resource, e.g. reading marketplace entries as reading apibindings. Writes
+// stay writes, so read access to the resource does not grant them. The name
+// of get, update, patch and delete requests is kept, so roles restricted to
+// resourceNames apply.
+func RemapResource(group, resource string) EquivalentFunc {
+	return func(a authorizer.Attributes) authorizer.Attributes {
+		record := authorizer.AttributesRecord{
+			User:            a.GetUser(),
+			Verb:            a.GetVerb(),
+			APIGroup:        group,
+			APIVersion:      "*",
+			Resource:        resource,
+			Subresource:     a.GetSubresource(),
+			ResourceRequest: true,
+		}
+		switch a.GetVerb() {
+		case "get", "update", "patch", "delete":
+			record.Name = a.GetName()
+		}
+		return record
+	}
+}
+
+// isDiscoveryPath returns whether path is one of the non-resource paths
+// clients read to discover the served APIs.
+func isDiscoveryPath(path string) bool {
+	path = strings.TrimSuffix(path, "/")
+	switch {
+	case path == "/api", path == "/apis", path == "/version", path == "/openapi/v2":
+		return true
+	case strings.HasPrefix(path, "/api/"):
+		// /api/<version>
+		return strings.Count(path, "/") == 2
+	case strings.HasPrefix(path, "/apis/"):
+		// /apis/<group> and /apis/<group>/<version>
+		return strings.Count(path, "/") <= 3
+	case strings.HasPrefix(path, "/openapi/v3"):
+		return true
+	}
+	return false
+}
+
+// NewSubjectAccessReviewAuthorizer returns an authorizer asking kcp with a
+// SubjectAccessReview in the requested workspace whether the user may perform
+// the equivalent request. Decisions are cached per workspace and attributes
+// for the TTLs in opts. Discovery requests are allowed for all authenticated
+// users, other non-resource requests are denied.
+func NewSubjectAccessReviewAuthorizer(client kcpkubernetesclientset.ClusterInterface, equivalent EquivalentFunc, opts delegated.Options) authorizer.Authorizer {
+	return delegated.NewCachingAuthorizer(client, func(ctx context.Context, cache delegated.Cache, a authorizer.Attributes) (authorizer.Decision, string, error) {
+		if !a.IsResourceRequest() {
+			switch {
+			case !isDiscoveryPath(a.GetPath()):
+				return authorizer.DecisionDeny, "only discovery is allowed for non-resource requests", nil
+			case a.GetVerb() != "get":
+				return authorizer.DecisionDeny, "discovery is read-only", nil
+			case !slices.Contains(a.GetUser().GetGroups(), user.AllAuthenticated):
+				return authorizer.DecisionDeny, "user is not authenticated", nil
+			}
+			return authorizer.DecisionAllow, "user is authenticated", nil
+		}
+
+		cluster := genericapirequest.ClusterFrom(ctx)
+		if cluster == nil || cluster.Name.Empty() {
+			return authorizer.DecisionDeny, "requests across workspaces are not supported", nil
+		}
+
+		authz, err := cache.Get(cluster.Name)
+		if err != nil {
+			return authorizer.DecisionNoOpinion, "", err
+		}
+
+		return authz.Authorize(ctx, equivalent(a))
+	}, delegated.CachingOptions{Options: opts})
+}
+
+// NewWorkspaceAuthorizers returns SubjectAccessReview authorizers per
+// workspace, for checks in other workspaces than the requested one. Decisions
+// are cached like in NewSubjectAccessReviewAuthorizer.
+func NewWorkspaceAuthorizers(client kcpkubernetesclientset.ClusterInterface, opts delegated.Options) delegated.Cache {
+	return delegated.NewCachingAuthorizer(client, nil, delegated.CachingOptions{Options: opts})
+}
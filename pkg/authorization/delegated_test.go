@@ -0,0 +1,161 @@
+package authorization
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	kcpkubernetesclientset "github.com/kcp-dev/client-go/kubernetes"
+	"github.com/kcp-dev/kcp/pkg/authorization/delegated"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/client-go/rest"
+
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+)
+
+func TestSubjectAccessReviewAuthorizer(t *testing.T) {
+	t.Parallel()
+
+	alice := &user.DefaultInfo{Name: "alice", Groups: []string{user.AllAuthenticated}}
+	bob := &user.DefaultInfo{Name: "bob", Groups: []string{user.AllAuthenticated}}
+	carol := &user.DefaultInfo{Name: "carol", Groups: []string{user.AllAuthenticated}}
+
+	var reviews atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reviews.Add(1)
+		assert.Equal(t, "/clusters/team-a/apis/authorization.k8s.io/v1/subjectaccessreviews", r.URL.Path)
+
+		var sar authorizationv1.SubjectAccessReview
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&sar))
+		attrs := sar.Spec.ResourceAttributes
+		assert.Equal(t, "apis.kcp.io", attrs.Group)
+		assert.Equal(t, "apibindings", attrs.Resource)
+
+		// alice may only read apibindings, carol only get the one named entry
+		switch sar.Spec.User {
+		case "alice":
+			sar.Status.Allowed = slices.Contains([]string{"get", "list", "watch"}, attrs.Verb)
+		case "carol":
+			sar.Status.Allowed = attrs.Verb == "get" && attrs.Name == "entry"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(&sar)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := kcpkubernetesclientset.NewForConfig(&rest.Config{Host: server.URL})
+	require.NoError(t, err)
+
+	authz := NewSubjectAccessReviewAuthorizer(client, RemapResource("apis.kcp.io", "apibindings"), delegated.Options{
+		AllowCacheTTL: time.Minute,
+		DenyCacheTTL:  time.Minute,
+	})
+
+	ctx := genericapirequest.WithCluster(context.Background(), genericapirequest.Cluster{Name: "team-a"})
+	resourceRequest := func(u user.Info) authorizer.Attributes {
+		return authorizer.AttributesRecord{
+			User:            u,
+			Verb:            "get",
+			APIGroup:        "ui.platform-mesh.io",
+			Resource:        "marketplaceentries",
+			Name:            "entry",
+			ResourceRequest: true,
+		}
+	}
+	writeRequest := func(u user.Info) authorizer.Attributes {
+		return authorizer.AttributesRecord{
+			User:            u,
+			Verb:            "update",
+			APIGroup:        "ui.platform-mesh.io",
+			Resource:        "marketplaceentries",
+			Name:            "entry",
+			ResourceRequest: true,
+		}
+	}
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		attr     authorizer.Attributes
+		expected authorizer.Decision
+	}{
+		{name: "allowed by kcp", ctx: ctx, attr: resourceRequest(alice), expected: authorizer.DecisionAllow},
+		{name: "not allowed by kcp", ctx: ctx, attr: resourceRequest(bob), expected: authorizer.DecisionNoOpinion},
+		{name: "writes are not allowed by read access", ctx: ctx, attr: writeRequest(alice), expected: authorizer.DecisionNoOpinion},
+		{name: "get allowed by a role restricted to resourceNames", ctx: ctx, attr: resourceRequest(carol), expected: authorizer.DecisionAllow},
+		{
+			name: "list not allowed by a role restricted to resourceNames",
+			ctx:  ctx,
+			attr: authorizer.AttributesRecord{
+				User:            carol,
+				Verb:            "list",
+				APIGroup:        "ui.platform-mesh.io",
+				Resource:        "marketplaceentries",
+				ResourceRequest: true,
+			},
+			expected: authorizer.DecisionNoOpinion,
+		},
+		{
+			name:     "wildcard requests are denied",
+			ctx:      genericapirequest.WithCluster(context.Background(), genericapirequest.Cluster{Wildcard: true}),
+			attr:     resourceRequest(alice),
+			expected: authorizer.DecisionDeny,
+		},
+		{
+			name:     "discovery is allowed for authenticated users",
+			ctx:      ctx,
+			attr:     authorizer.AttributesRecord{User: bob, Verb: "get", Path: "/apis"},
+			expected: authorizer.DecisionAllow,
+		},
+		{
+			name:     "group discovery is allowed for authenticated users",
+			ctx:      ctx,
+			attr:     authorizer.AttributesRecord{User: bob, Verb: "get", Path: "/apis/ui.platform-mesh.io/v1alpha1"},
+			expected: authorizer.DecisionAllow,
+		},
+		{
+			name:     "other non-resource requests are denied",
+			ctx:      ctx,
+			attr:     authorizer.AttributesRecord{User: bob, Verb: "get", Path: "/metrics"},
+			expected: authorizer.DecisionDeny,
+		},
+		{
+			name:     "non-resource writes are denied",
+			ctx:      ctx,
+			attr:     authorizer.AttributesRecord{User: bob, Verb: "post", Path: "/apis"},
+			expected: authorizer.DecisionDeny,
+		},
+		{
+			name:     "discovery is denied for unauthenticated users",
+			ctx:      ctx,
+			attr:     authorizer.AttributesRecord{User: &user.DefaultInfo{Name: user.Anonymous}, Verb: "get", Path: "/apis"},
+			expected: authorizer.DecisionDeny,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision, _, err := authz.Authorize(tt.ctx, tt.attr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, decision)
+		})
+	}
+
+	// decisions are cached
+	reviewsBefore := reviews.Load()
+	decision, _, err := authz.Authorize(ctx, resourceRequest(alice))
+	require.NoError(t, err)
+	assert.Equal(t, authorizer.DecisionAllow, decision)
+	assert.Equal(t, reviewsBefore, reviews.Load())
+}
@@ -1,6 +1,13 @@
 package config
 
-import "github.com/spf13/pflag"
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+)
 
 type ServiceConfig struct {
 	Kubeconfig        string
@@ -10,20 +17,142 @@ type ServiceConfig struct {
 	MainEntityName    string
 	AccountEntityName string
 
+	// EntityTypeMappings maps a WorkspaceType (e.g. "root:project") to the
+	// entity name used to select contentconfigurations for workspaces of that type.
+	EntityTypeMappings map[string]string
+
+	// ConsumerSelectorAnnotation is the contentconfiguration annotation holding a
+	// label selector the consuming workspace has to match for the content to show.
+	// Consumer selectors are only evaluated if it is set.
+	ConsumerSelectorAnnotation string
+
+	// InheritContentConfigurations collects contentconfigurations labeled with
+	// InheritableLabel from all ancestor workspaces of the requested one.
+	InheritContentConfigurations bool
+	InheritableLabel             string
+
+	// ResourceSchemaName pins the contentconfigurations APIResourceSchema. If
+	// empty, the schema is discovered from ResourceAPIExportName.
 	ResourceSchemaName      string
 	ResourceSchemaWorkspace string
+	// ResourceAPIExportName is the APIExport in ResourceSchemaWorkspace that
+	// references the current contentconfigurations APIResourceSchema.
+	ResourceAPIExportName string
+	// ResourceSchemaRefreshInterval is the resync period of the watches on the
+	// APIExport and APIResourceSchemas the served schema is resolved from.
+	ResourceSchemaRefreshInterval time.Duration
+
+	// ContentSourceConcurrency limits how many export and provider workspaces
+	// are listed in parallel for a single contentconfigurations request.
+	ContentSourceConcurrency int
+	// ContentSourceTimeout bounds the list request against a single source.
+	ContentSourceTimeout time.Duration
+	// IncludeInactiveBindings includes contentconfigurations of APIBindings that
+	// are not bound, annotated as inactive. Meant for diagnostics only.
+	IncludeInactiveBindings bool
+
+	// EnableContentConfigurationWrites forwards create, update, patch and delete
+	// of contentconfigurations to the requested workspace, impersonating the caller.
+	EnableContentConfigurationWrites bool
+	// ImpersonateWorkspaceReads reads the requested workspace and its APIBindings
+	// as the caller instead of the service identity. Export and provider
+	// workspaces are still read with the service identity.
+	ImpersonateWorkspaceReads bool
+
+	// ProviderWorkspaces lists the workspaces providing default contentconfigurations
+	// to all workspaces, as "<path>" or "<path>=<entity label>". If empty,
+	// ResourceSchemaWorkspace is the only provider workspace.
+	ProviderWorkspaces []string
 
 	ResourceAPIExportEndpointSliceName string
+
+	// HideUninstallableMarketplaceEntries leaves marketplace entries out that
+	// the caller may not install and that are not installed yet.
+	HideUninstallableMarketplaceEntries bool
+	// EnableAnonymousMarketplaceCatalog serves the marketplace entries without
+	// installed state to anonymous users below /services/marketplace/catalog.
+	EnableAnonymousMarketplaceCatalog bool
+
+	// AuthenticationMode selects how bearer tokens are authenticated, see
+	// AuthenticationModeKCP and AuthenticationModeJWT.
+	AuthenticationMode string
+	// OIDCIssuersFile configures the issuers of AuthenticationModeJWT.
+	OIDCIssuersFile string
+
+	// AuthorizationAllowCacheTTL and AuthorizationDenyCacheTTL bound how long the
+	// SubjectAccessReview decisions of the virtual workspace authorizers are cached.
+	AuthorizationAllowCacheTTL time.Duration
+	AuthorizationDenyCacheTTL  time.Duration
+
+	// UserInfoEndpoint is an OIDC userinfo endpoint the identity of bearer tokens
+	// is resolved from. If empty, a SelfSubjectReview in the requested workspace is used.
+	// The prefixes have to match kcp's --oidc-username-prefix and
+	// --oidc-groups-prefix, so kcp knows the resolved users.
+	UserInfoEndpoint       string
+	UserInfoUsernameClaim  string
+	UserInfoUsernamePrefix string
+	UserInfoGroupsClaim    string
+	UserInfoGroupsPrefix   string
+
+	// TokenCacheSize bounds the number of cached token authentication results,
+	// keyed by token and workspace. Caching is disabled if it is 0.
+	TokenCacheSize       int
+	TokenCacheSuccessTTL time.Duration
+	TokenCacheFailureTTL time.Duration
+
+	// TenantRequestQPS and TenantRequestBurst limit the requests per user and
+	// logical cluster. TenantFanOutQPS and TenantFanOutBurst limit the workspaces
+	// these requests list contentconfigurations from. A QPS of 0 disables the
+	// limit, which is the default. The bursts are sized for a portal page load,
+	// which makes dozens of requests at once.
+	TenantRequestQPS   float64
+	TenantRequestBurst int
+	TenantFanOutQPS    float64
+	TenantFanOutBurst  int
+
+	// ContentConfigurationAPIExportEndpointSliceName enables serving export and
+	// provider contentconfigurations from an informer cache when set.
+	ContentConfigurationAPIExportEndpointSliceName string
 }
 
+const (
+	// AuthenticationModeKCP authenticates tokens against the requested kcp workspace.
+	AuthenticationModeKCP = "kcp"
+	// AuthenticationModeJWT validates tokens locally against configured OIDC issuers.
+	AuthenticationModeJWT = "jwt"
+)
+
 func NewServiceConfig() ServiceConfig {
 	return ServiceConfig{
 		EntityLabel:             "ui.platform-mesh.ui/entity",
 		ContentForLabel:         "ui.platform-mesh.io/content-for",
 		MainEntityName:          "main",
 		AccountEntityName:       "core_platform-mesh_io_account",
-		ResourceSchemaName:      "v250704-6d57f16.contentconfigurations.ui.platform-mesh.io",
 		ResourceSchemaWorkspace: "root:openmfp-system",
+
+		ResourceAPIExportName:         "ui.platform-mesh.io",
+		ResourceSchemaRefreshInterval: time.Minute,
+
+		InheritableLabel: "ui.platform-mesh.io/inheritable",
+
+		AuthenticationMode:    AuthenticationModeKCP,
+		UserInfoUsernameClaim: "email",
+		UserInfoGroupsClaim:   "groups",
+
+		AuthorizationAllowCacheTTL: 5 * time.Minute,
+		AuthorizationDenyCacheTTL:  30 * time.Second,
+
+		TokenCacheSize:       4096,
+		TokenCacheSuccessTTL: time.Minute,
+		TokenCacheFailureTTL: 10 * time.Second,
+
+		TenantRequestQPS:   0,
+		TenantRequestBurst: 100,
+		TenantFanOutQPS:    0,
+		TenantFanOutBurst:  500,
+
+		ContentSourceConcurrency: 10,
+		ContentSourceTimeout:     5 * time.Second,
 	}
 }
 
@@ -34,12 +163,226 @@ func (c *ServiceConfig) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&c.ContentForLabel, "content-for-label", c.ContentForLabel, "Set the content-for label")
 	fs.StringVar(&c.MainEntityName, "main-entity-name", c.MainEntityName, "Set the main entity name")
 	fs.StringVar(&c.AccountEntityName, "account-entity-name", c.AccountEntityName, "Set the account entity name")
-	fs.StringVar(&c.ResourceSchemaName, "resource-schema-name", c.ResourceSchemaName, "Set the resource schema name")
+	fs.StringToStringVar(
+		&c.EntityTypeMappings,
+		"entity-type-mappings",
+		c.EntityTypeMappings,
+		"Set the mapping of workspace types to entity names (e.g. root:project=project,root:team=team), workspaces of unmapped types are rejected",
+	)
+	fs.StringVar(
+		&c.ConsumerSelectorAnnotation,
+		"consumer-selector-annotation",
+		c.ConsumerSelectorAnnotation,
+		"Set the contentconfiguration annotation holding a label selector for the consuming workspace (disabled if empty)",
+	)
+	fs.BoolVar(
+		&c.InheritContentConfigurations,
+		"inherit-contentconfigurations",
+		c.InheritContentConfigurations,
+		"Include inheritable contentconfigurations of ancestor workspaces, the nearest definition of a name wins",
+	)
+	fs.StringVar(
+		&c.InheritableLabel,
+		"inheritable-label",
+		c.InheritableLabel,
+		"Set the label marking contentconfigurations as inherited by descendant workspaces",
+	)
+	fs.StringVar(&c.ResourceSchemaName, "resource-schema-name", c.ResourceSchemaName, "Set the resource schema name (discovered from the resource APIExport if empty)")
 	fs.StringVar(&c.ResourceSchemaWorkspace, "resource-schema-workspace", c.ResourceSchemaWorkspace, "Set the resource schema workspace")
+	fs.StringVar(
+		&c.ResourceAPIExportName,
+		"resource-apiexport-name",
+		c.ResourceAPIExportName,
+		"Set the APIExport in the resource schema workspace used to discover the resource schema",
+	)
+	fs.DurationVar(
+		&c.ResourceSchemaRefreshInterval,
+		"resource-schema-refresh-interval",
+		c.ResourceSchemaRefreshInterval,
+		"Set the resync period of the resource schema watches",
+	)
+	fs.IntVar(
+		&c.ContentSourceConcurrency,
+		"content-source-concurrency",
+		c.ContentSourceConcurrency,
+		"Set the maximum number of contentconfiguration sources listed in parallel",
+	)
+	fs.DurationVar(
+		&c.ContentSourceTimeout,
+		"content-source-timeout",
+		c.ContentSourceTimeout,
+		"Set the timeout for listing contentconfigurations from a single source",
+	)
+	fs.BoolVar(
+		&c.IncludeInactiveBindings,
+		"include-inactive-bindings",
+		c.IncludeInactiveBindings,
+		"Include contentconfigurations of APIBindings that are not bound, annotated as inactive (diagnostics only)",
+	)
+	fs.BoolVar(
+		&c.EnableContentConfigurationWrites,
+		"enable-contentconfiguration-writes",
+		c.EnableContentConfigurationWrites,
+		"Forward contentconfiguration writes to the requested workspace as the caller (requires permission to impersonate users)",
+	)
+	fs.BoolVar(
+		&c.ImpersonateWorkspaceReads,
+		"impersonate-workspace-reads",
+		c.ImpersonateWorkspaceReads,
+		"Read the requested workspace and its APIBindings as the caller (requires permission to impersonate users)",
+	)
+	fs.StringSliceVar(
+		&c.ProviderWorkspaces,
+		"provider-workspaces",
+		c.ProviderWorkspaces,
+		"Set the workspaces providing default contentconfigurations as <path>[=<entity label>] (defaults to the resource schema workspace)",
+	)
 	fs.StringVar(
 		&c.ResourceAPIExportEndpointSliceName,
 		"resource-apiexport-endpointslice-name",
 		c.ResourceAPIExportEndpointSliceName,
 		"Set the resource APIExport EndpointSlice name",
 	)
+	fs.BoolVar(
+		&c.HideUninstallableMarketplaceEntries,
+		"hide-uninstallable-marketplace-entries",
+		c.HideUninstallableMarketplaceEntries,
+		"Hide marketplace entries the caller is not allowed to install",
+	)
+	fs.BoolVar(
+		&c.EnableAnonymousMarketplaceCatalog,
+		"enable-anonymous-marketplace-catalog",
+		c.EnableAnonymousMarketplaceCatalog,
+		"Serve a read-only marketplace catalog without installed state to anonymous users",
+	)
+	fs.StringVar(
+		&c.AuthenticationMode,
+		"authentication-mode",
+		c.AuthenticationMode,
+		"Set how bearer tokens are authenticated, kcp (against the requested workspace) or jwt (locally against the oidc issuers file)",
+	)
+	fs.StringVar(&c.OIDCIssuersFile, "oidc-issuers-file", c.OIDCIssuersFile, "Set the file configuring the oidc issuers of the jwt authentication mode")
+	fs.DurationVar(
+		&c.AuthorizationAllowCacheTTL,
+		"authorization-allow-cache-ttl",
+		c.AuthorizationAllowCacheTTL,
+		"Set how long allowing SubjectAccessReview decisions are cached",
+	)
+	fs.DurationVar(
+		&c.AuthorizationDenyCacheTTL,
+		"authorization-deny-cache-ttl",
+		c.AuthorizationDenyCacheTTL,
+		"Set how long denying SubjectAccessReview decisions are cached",
+	)
+	fs.StringVar(
+		&c.UserInfoEndpoint,
+		"userinfo-endpoint",
+		c.UserInfoEndpoint,
+		"Set the OIDC userinfo endpoint used to resolve the user of a token (a SelfSubjectReview against kcp is used if empty)",
+	)
+	fs.StringVar(&c.UserInfoUsernameClaim, "userinfo-username-claim", c.UserInfoUsernameClaim, "Set the userinfo claim used as username")
+	fs.StringVar(&c.UserInfoGroupsClaim, "userinfo-groups-claim", c.UserInfoGroupsClaim, "Set the userinfo claim used as groups")
+	fs.StringVar(
+		&c.UserInfoUsernamePrefix,
+		"userinfo-username-prefix",
+		c.UserInfoUsernamePrefix,
+		"Set the prefix of usernames resolved from the userinfo endpoint (has to match kcp's --oidc-username-prefix)",
+	)
+	fs.StringVar(
+		&c.UserInfoGroupsPrefix,
+		"userinfo-groups-prefix",
+		c.UserInfoGroupsPrefix,
+		"Set the prefix of groups resolved from the userinfo endpoint (has to match kcp's --oidc-groups-prefix)",
+	)
+	fs.IntVar(&c.TokenCacheSize, "token-cache-size", c.TokenCacheSize, "Set the maximum number of cached token authentication results (disabled if 0)")
+	fs.DurationVar(
+		&c.TokenCacheSuccessTTL,
+		"token-cache-success-ttl",
+		c.TokenCacheSuccessTTL,
+		"Set how long successful token authentications are cached, at most until the token expires",
+	)
+	fs.DurationVar(&c.TokenCacheFailureTTL, "token-cache-failure-ttl", c.TokenCacheFailureTTL, "Set how long failed token authentications are cached")
+	fs.Float64Var(&c.TenantRequestQPS, "tenant-request-qps", c.TenantRequestQPS, "Set the requests per second allowed per user and logical cluster (disabled if 0)")
+	fs.IntVar(&c.TenantRequestBurst, "tenant-request-burst", c.TenantRequestBurst, "Set the request burst allowed per user and logical cluster")
+	fs.Float64Var(
+		&c.TenantFanOutQPS,
+		"tenant-fanout-qps",
+		c.TenantFanOutQPS,
+		"Set the workspaces per second contentconfigurations may be listed from per user and logical cluster (disabled if 0)",
+	)
+	fs.IntVar(&c.TenantFanOutBurst, "tenant-fanout-burst", c.TenantFanOutBurst, "Set the workspace burst contentconfigurations may be listed from per user and logical cluster")
+	fs.StringVar(
+		&c.ContentConfigurationAPIExportEndpointSliceName,
+		"contentconfiguration-apiexport-endpointslice-name",
+		c.ContentConfigurationAPIExportEndpointSliceName,
+		"Set the contentconfigurations APIExport EndpointSlice name used to cache export and provider contentconfigurations (disabled if empty)",
+	)
+}
+
+// ProviderWorkspace is a workspace providing default contentconfigurations,
+// selected by the entity type of the requested workspace in EntityLabel.
+type ProviderWorkspace struct {
+	Path        string
+	EntityLabel string
+}
+
+// ProviderWorkspaceRules returns the configured provider workspaces, falling
+// back to ResourceSchemaWorkspace. Entries without an entity label use EntityLabel.
+func (c ServiceConfig) ProviderWorkspaceRules() []ProviderWorkspace {
+	if len(c.ProviderWorkspaces) == 0 {
+		return []ProviderWorkspace{{Path: c.ResourceSchemaWorkspace, EntityLabel: c.EntityLabel}}
+	}
+
+	providers := make([]ProviderWorkspace, 0, len(c.ProviderWorkspaces))
+	for _, entry := range c.ProviderWorkspaces {
+		path, entityLabel, _ := strings.Cut(entry, "=")
+		if entityLabel == "" {
+			entityLabel = c.EntityLabel
+		}
+		providers = append(providers, ProviderWorkspace{Path: path, EntityLabel: entityLabel})
+	}
+
+	return providers
+}
+
+// Validate checks the configuration for values the service cannot start with.
+// The content of OIDCIssuersFile is validated when the issuers are loaded.
+func (c ServiceConfig) Validate() []error {
+	var errs []error
+
+	switch c.AuthenticationMode {
+	case AuthenticationModeKCP:
+	case AuthenticationModeJWT:
+		if c.OIDCIssuersFile == "" {
+			errs = append(errs, fmt.Errorf("--oidc-issuers-file is required with authentication mode %q", AuthenticationModeJWT))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unknown authentication mode %q, expected %q or %q", c.AuthenticationMode, AuthenticationModeKCP, AuthenticationModeJWT))
+	}
+
+	seen := map[string]bool{}
+	for _, provider := range c.ProviderWorkspaceRules() {
+		switch {
+		case provider.Path == "":
+			errs = append(errs, errors.New("--provider-workspaces contains an entry without a workspace path"))
+		case seen[provider.Path]:
+			errs = append(errs, fmt.Errorf("--provider-workspaces contains %s more than once", provider.Path))
+		}
+		seen[provider.Path] = true
+	}
+
+	if c.TenantRequestQPS < 0 {
+		errs = append(errs, fmt.Errorf("--tenant-request-qps must not be negative, got %v", c.TenantRequestQPS))
+	}
+	if c.TenantRequestBurst <= 0 {
+		errs = append(errs, fmt.Errorf("--tenant-request-burst must be positive, got %d", c.TenantRequestBurst))
+	}
+	if c.TenantFanOutQPS < 0 {
+		errs = append(errs, fmt.Errorf("--tenant-fanout-qps must not be negative, got %v", c.TenantFanOutQPS))
+	}
+	if c.TenantFanOutBurst <= 0 {
+		errs = append(errs, fmt.Errorf("--tenant-fanout-burst must be positive, got %d", c.TenantFanOutBurst))
+	}
+
+	return errs
 }
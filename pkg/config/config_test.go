@@ -2,6 +2,7 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/require"
@@ -18,9 +19,40 @@ func TestNewServiceConfigDefaults(t *testing.T) {
 	require.Equal(t, "ui.platform-mesh.io/content-for", cfg.ContentForLabel)
 	require.Equal(t, "main", cfg.MainEntityName)
 	require.Equal(t, "core_platform-mesh_io_account", cfg.AccountEntityName)
-	require.Equal(t, "v250704-6d57f16.contentconfigurations.ui.platform-mesh.io", cfg.ResourceSchemaName)
+	require.Empty(t, cfg.EntityTypeMappings)
+	require.Empty(t, cfg.ConsumerSelectorAnnotation)
+	require.False(t, cfg.InheritContentConfigurations)
+	require.Equal(t, "ui.platform-mesh.io/inheritable", cfg.InheritableLabel)
+	require.Equal(t, "", cfg.ResourceSchemaName)
 	require.Equal(t, "root:openmfp-system", cfg.ResourceSchemaWorkspace)
+	require.Equal(t, "ui.platform-mesh.io", cfg.ResourceAPIExportName)
+	require.Equal(t, time.Minute, cfg.ResourceSchemaRefreshInterval)
+	require.Equal(t, 10, cfg.ContentSourceConcurrency)
+	require.Equal(t, 5*time.Second, cfg.ContentSourceTimeout)
+	require.False(t, cfg.IncludeInactiveBindings)
+	require.False(t, cfg.EnableContentConfigurationWrites)
+	require.False(t, cfg.ImpersonateWorkspaceReads)
+	require.Empty(t, cfg.ProviderWorkspaces)
 	require.Equal(t, "", cfg.ResourceAPIExportEndpointSliceName)
+	require.False(t, cfg.HideUninstallableMarketplaceEntries)
+	require.False(t, cfg.EnableAnonymousMarketplaceCatalog)
+	require.Equal(t, AuthenticationModeKCP, cfg.AuthenticationMode)
+	require.Equal(t, "", cfg.OIDCIssuersFile)
+	require.Equal(t, 5*time.Minute, cfg.AuthorizationAllowCacheTTL)
+	require.Equal(t, 30*time.Second, cfg.AuthorizationDenyCacheTTL)
+	require.Equal(t, 0.0, cfg.TenantRequestQPS)
+	require.Equal(t, 100, cfg.TenantRequestBurst)
+	require.Equal(t, 0.0, cfg.TenantFanOutQPS)
+	require.Equal(t, 500, cfg.TenantFanOutBurst)
+	require.Equal(t, "", cfg.UserInfoEndpoint)
+	require.Equal(t, "email", cfg.UserInfoUsernameClaim)
+	require.Equal(t, "groups", cfg.UserInfoGroupsClaim)
+	require.Equal(t, "", cfg.UserInfoUsernamePrefix)
+	require.Equal(t, "", cfg.UserInfoGroupsPrefix)
+	require.Equal(t, 4096, cfg.TokenCacheSize)
+	require.Equal(t, time.Minute, cfg.TokenCacheSuccessTTL)
+	require.Equal(t, 10*time.Second, cfg.TokenCacheFailureTTL)
+	require.Equal(t, "", cfg.ContentConfigurationAPIExportEndpointSliceName)
 }
 
 func TestServiceConfigAddFlagsParsesValues(t *testing.T) {
@@ -37,9 +69,40 @@ func TestServiceConfigAddFlagsParsesValues(t *testing.T) {
 		"--content-for-label=custom.content/for",
 		"--main-entity-name=home",
 		"--account-entity-name=core_platform-mesh_io_customer",
+		"--entity-type-mappings=root:project=project,root:team=team",
+		"--consumer-selector-annotation=custom.io/requires",
+		"--inherit-contentconfigurations",
+		"--inheritable-label=custom.io/inheritable",
 		"--resource-schema-name=v1.contentconfigurations.ui.platform-mesh.io",
 		"--resource-schema-workspace=root:orgs",
+		"--resource-apiexport-name=custom.platform-mesh.io",
+		"--resource-schema-refresh-interval=30s",
+		"--content-source-concurrency=4",
+		"--content-source-timeout=2s",
+		"--include-inactive-bindings",
+		"--enable-contentconfiguration-writes",
+		"--impersonate-workspace-reads",
+		"--provider-workspaces=root:core,root:billing=billing.io/entity",
 		"--resource-apiexport-endpointslice-name=ui.platform-mesh.io",
+		"--hide-uninstallable-marketplace-entries",
+		"--enable-anonymous-marketplace-catalog",
+		"--authentication-mode=jwt",
+		"--oidc-issuers-file=/etc/issuers.yaml",
+		"--authorization-allow-cache-ttl=1m",
+		"--authorization-deny-cache-ttl=5s",
+		"--userinfo-endpoint=https://idp.example.com/userinfo",
+		"--userinfo-username-claim=preferred_username",
+		"--userinfo-groups-claim=roles",
+		"--userinfo-username-prefix=oidc:",
+		"--userinfo-groups-prefix=oidc-groups:",
+		"--token-cache-size=100",
+		"--token-cache-success-ttl=5m",
+		"--token-cache-failure-ttl=1s",
+		"--tenant-request-qps=2.5",
+		"--tenant-request-burst=5",
+		"--tenant-fanout-qps=0",
+		"--tenant-fanout-burst=10",
+		"--contentconfiguration-apiexport-endpointslice-name=contentconfigurations.ui.platform-mesh.io",
 	})
 	require.NoError(t, err)
 
@@ -49,8 +112,134 @@ func TestServiceConfigAddFlagsParsesValues(t *testing.T) {
 	require.Equal(t, "custom.content/for", cfg.ContentForLabel)
 	require.Equal(t, "home", cfg.MainEntityName)
 	require.Equal(t, "core_platform-mesh_io_customer", cfg.AccountEntityName)
+	require.Equal(t, map[string]string{"root:project": "project", "root:team": "team"}, cfg.EntityTypeMappings)
+	require.Equal(t, "custom.io/requires", cfg.ConsumerSelectorAnnotation)
+	require.True(t, cfg.InheritContentConfigurations)
+	require.Equal(t, "custom.io/inheritable", cfg.InheritableLabel)
 	require.Equal(t, "v1.contentconfigurations.ui.platform-mesh.io", cfg.ResourceSchemaName)
 	require.Equal(t, "root:orgs", cfg.ResourceSchemaWorkspace)
+	require.Equal(t, "custom.platform-mesh.io", cfg.ResourceAPIExportName)
+	require.Equal(t, 30*time.Second, cfg.ResourceSchemaRefreshInterval)
+	require.Equal(t, 4, cfg.ContentSourceConcurrency)
+	require.Equal(t, 2*time.Second, cfg.ContentSourceTimeout)
+	require.True(t, cfg.IncludeInactiveBindings)
+	require.True(t, cfg.EnableContentConfigurationWrites)
+	require.True(t, cfg.ImpersonateWorkspaceReads)
+	require.Equal(t, []string{"root:core", "root:billing=billing.io/entity"}, cfg.ProviderWorkspaces)
 	require.Equal(t, "ui.platform-mesh.io", cfg.ResourceAPIExportEndpointSliceName)
+	require.True(t, cfg.HideUninstallableMarketplaceEntries)
+	require.True(t, cfg.EnableAnonymousMarketplaceCatalog)
+	require.Equal(t, AuthenticationModeJWT, cfg.AuthenticationMode)
+	require.Equal(t, "/etc/issuers.yaml", cfg.OIDCIssuersFile)
+	require.Equal(t, time.Minute, cfg.AuthorizationAllowCacheTTL)
+	require.Equal(t, 5*time.Second, cfg.AuthorizationDenyCacheTTL)
+	require.Equal(t, "https://idp.example.com/userinfo", cfg.UserInfoEndpoint)
+	require.Equal(t, "preferred_username", cfg.UserInfoUsernameClaim)
+	require.Equal(t, "roles", cfg.UserInfoGroupsClaim)
+	require.Equal(t, "oidc:", cfg.UserInfoUsernamePrefix)
+	require.Equal(t, "oidc-groups:", cfg.UserInfoGroupsPrefix)
+	require.Equal(t, 100, cfg.TokenCacheSize)
+	require.Equal(t, 5*time.Minute, cfg.TokenCacheSuccessTTL)
+	require.Equal(t, time.Second, cfg.TokenCacheFailureTTL)
+	require.Equal(t, 2.5, cfg.TenantRequestQPS)
+	require.Equal(t, 5, cfg.TenantRequestBurst)
+	require.Equal(t, 0.0, cfg.TenantFanOutQPS)
+	require.Equal(t, 10, cfg.TenantFanOutBurst)
+	require.Equal(t, "contentconfigurations.ui.platform-mesh.io", cfg.ContentConfigurationAPIExportEndpointSliceName)
 	require.Empty(t, fs.Args())
 }
+
+func TestServiceConfigProviderWorkspaceRules(t *testing.T) {
+	t.Parallel()
+
+	cfg := NewServiceConfig()
+	require.Equal(t, []ProviderWorkspace{
+		{Path: "root:openmfp-system", EntityLabel: "ui.platform-mesh.ui/entity"},
+	}, cfg.ProviderWorkspaceRules())
+
+	cfg.ProviderWorkspaces = []string{"root:core", "root:billing=billing.io/entity", "root:observability="}
+	require.Equal(t, []ProviderWorkspace{
+		{Path: "root:core", EntityLabel: "ui.platform-mesh.ui/entity"},
+		{Path: "root:billing", EntityLabel: "billing.io/entity"},
+		{Path: "root:observability", EntityLabel: "ui.platform-mesh.ui/entity"},
+	}, cfg.ProviderWorkspaceRules())
+}
+
+func TestServiceConfigValidate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		modify      func(cfg *ServiceConfig)
+		expectedErr []string
+	}{
+		{
+			name:   "accepts the defaults",
+			modify: func(cfg *ServiceConfig) {},
+		},
+		{
+			name: "accepts the jwt mode with an issuers file",
+			modify: func(cfg *ServiceConfig) {
+				cfg.AuthenticationMode = AuthenticationModeJWT
+				cfg.OIDCIssuersFile = "/etc/issuers.yaml"
+			},
+		},
+		{
+			name:        "rejects an unknown authentication mode",
+			modify:      func(cfg *ServiceConfig) { cfg.AuthenticationMode = "oidc" },
+			expectedErr: []string{`unknown authentication mode "oidc"`},
+		},
+		{
+			name:        "rejects the jwt mode without issuers",
+			modify:      func(cfg *ServiceConfig) { cfg.AuthenticationMode = AuthenticationModeJWT },
+			expectedErr: []string{"--oidc-issuers-file is required"},
+		},
+		{
+			name:        "rejects empty provider workspaces",
+			modify:      func(cfg *ServiceConfig) { cfg.ProviderWorkspaces = []string{"root:core", "=billing.io/entity"} },
+			expectedErr: []string{"entry without a workspace path"},
+		},
+		{
+			name: "rejects duplicate provider workspaces",
+			modify: func(cfg *ServiceConfig) {
+				cfg.ProviderWorkspaces = []string{"root:core", "root:core=billing.io/entity"}
+			},
+			expectedErr: []string{"contains root:core more than once"},
+		},
+		{
+			name:        "rejects an empty resource schema workspace as provider",
+			modify:      func(cfg *ServiceConfig) { cfg.ResourceSchemaWorkspace = "" },
+			expectedErr: []string{"entry without a workspace path"},
+		},
+		{
+			name: "rejects non-positive limiter values",
+			modify: func(cfg *ServiceConfig) {
+				cfg.TenantRequestQPS = -1
+				cfg.TenantRequestBurst = 0
+				cfg.TenantFanOutQPS = -1
+				cfg.TenantFanOutBurst = -5
+			},
+			expectedErr: []string{
+				"--tenant-request-qps must not be negative",
+				"--tenant-request-burst must be positive",
+				"--tenant-fanout-qps must not be negative",
+				"--tenant-fanout-burst must be positive",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := NewServiceConfig()
+			tt.modify(&cfg)
+
+			errs := cfg.Validate()
+			require.Len(t, errs, len(tt.expectedErr))
+			for i, expected := range tt.expectedErr {
+				require.ErrorContains(t, errs[i], expected)
+			}
+		})
+	}
+}
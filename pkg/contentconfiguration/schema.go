@@ -0,0 +1,221 @@
+package contentconfiguration
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kcp-dev/client-go/dynamic"
+	"github.com/kcp-dev/logicalcluster/v3"
+	apisv1alpha1 "github.com/kcp-dev/sdk/apis/apis/v1alpha1"
+	"github.com/platform-mesh/virtual-workspaces/pkg/config"
+	"github.com/platform-mesh/virtual-workspaces/pkg/storage"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	k8sdynamic "k8s.io/client-go/dynamic"
+)
+
+var (
+	apiExportGVR = schema.GroupVersionResource{
+		Group:    "apis.kcp.io",
+		Version:  "v1alpha2",
+		Resource: "apiexports",
+	}
+	apiResourceSchemaGVR = schema.GroupVersionResource{
+		Group:    "apis.kcp.io",
+		Version:  "v1alpha1",
+		Resource: "apiresourceschemas",
+	}
+)
+
+// resourceSchemaSource keeps track of the APIResourceSchema served for
+// contentconfigurations. Unless a schema name is pinned in the config, the
+// schema is discovered from the APIExport in the resource schema workspace.
+// Both are watched, so new schemas are picked up without a restart.
+type resourceSchemaSource struct {
+	cfg config.ServiceConfig
+
+	exports cache.SharedIndexInformer
+	schemas cache.SharedIndexInformer
+
+	// syncLock serializes syncs triggered by events of both informers.
+	syncLock sync.Mutex
+	current  atomic.Pointer[apisv1alpha1.APIResourceSchema]
+	onChange []func()
+}
+
+func newResourceSchemaSource(client dynamic.ClusterInterface, cfg config.ServiceConfig) *resourceSchemaSource {
+	workspace := client.Cluster(logicalcluster.NewPath(cfg.ResourceSchemaWorkspace))
+
+	s := &resourceSchemaSource{cfg: cfg}
+	s.schemas = newWorkspaceInformer(workspace.Resource(apiResourceSchemaGVR), "", cfg.ResourceSchemaRefreshInterval)
+	if cfg.ResourceSchemaName == "" {
+		s.exports = newWorkspaceInformer(workspace.Resource(apiExportGVR), cfg.ResourceAPIExportName, cfg.ResourceSchemaRefreshInterval)
+	}
+
+	return s
+}
+
+// newWorkspaceInformer returns an informer for the resources of a single
+// workspace, limited to the object with the given name if it is not empty.
+func newWorkspaceInformer(client k8sdynamic.ResourceInterface, name string, resync time.Duration) cache.SharedIndexInformer {
+	withName := func(options *metav1.ListOptions) {
+		if name != "" {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+		}
+	}
+
+	return cache.NewSharedIndexInformer(&cache.ListWatch{
+		ListWithContextFunc: func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+			withName(&options)
+			return client.List(ctx, options)
+		},
+		WatchFuncWithContext: func(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+			withName(&options)
+			return client.Watch(ctx, options)
+		},
+	}, &unstructured.Unstructured{}, resync, cache.Indexers{})
+}
+
+// OnChange registers fn to be called whenever a different schema is served.
+// It must be called before Start.
+func (s *resourceSchemaSource) OnChange(fn func()) {
+	s.onChange = append(s.onChange, fn)
+}
+
+// Get returns the currently served schema, or nil before the first successful sync.
+func (s *resourceSchemaSource) Get() *apisv1alpha1.APIResourceSchema {
+	return s.current.Load()
+}
+
+// Start runs the informers until ctx is done and returns once they are synced.
+// The schema itself is resolved on sync and on every informer event.
+func (s *resourceSchemaSource) Start(ctx context.Context) error {
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(any) { s.syncAndLog() },
+		UpdateFunc: func(any, any) { s.syncAndLog() },
+		DeleteFunc: func(any) { s.syncAndLog() },
+	}
+
+	synced := []cache.InformerSynced{}
+	for _, informer := range []cache.SharedIndexInformer{s.exports, s.schemas} {
+		if informer == nil {
+			continue
+		}
+		if _, err := informer.AddEventHandler(handler); err != nil {
+			return err
+		}
+		go informer.RunWithContext(ctx)
+		synced = append(synced, informer.HasSynced)
+	}
+
+	if !cache.WaitForNamedCacheSyncWithContext(ctx, synced...) {
+		return fmt.Errorf("failed to sync contentconfigurations apiresourceschema informers")
+	}
+
+	return nil
+}
+
+func (s *resourceSchemaSource) syncAndLog() {
+	if err := s.sync(); err != nil {
+		klog.ErrorS(err, "failed to sync contentconfigurations apiresourceschema")
+	}
+}
+
+// sync resolves the schema from the informer caches and notifies OnChange
+// handlers if it differs from the served one. On errors the previously
+// resolved schema is kept.
+func (s *resourceSchemaSource) sync() error {
+	s.syncLock.Lock()
+	defer s.syncLock.Unlock()
+
+	name, err := s.resolveName()
+	if err != nil {
+		return err
+	}
+
+	obj, exists, err := s.schemas.GetStore().GetByKey(name)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("apiresourceschema %s not found in %s", name, s.cfg.ResourceSchemaWorkspace)
+	}
+	rawResourceSchema := obj.(*unstructured.Unstructured)
+
+	current := s.current.Load()
+	if current != nil && current.UID == rawResourceSchema.GetUID() && current.ResourceVersion == rawResourceSchema.GetResourceVersion() {
+		return nil
+	}
+
+	var resourceSchema apisv1alpha1.APIResourceSchema
+	err = runtime.DefaultUnstructuredConverter.FromUnstructured(rawResourceSchema.Object, &resourceSchema)
+	if err != nil {
+		return fmt.Errorf("failed to convert apiresourceschema %s: %w", name, err)
+	}
+
+	klog.InfoS("serving contentconfigurations apiresourceschema", "schema", name, "resourceVersion", resourceSchema.ResourceVersion)
+	s.current.Store(&resourceSchema)
+
+	for _, fn := range s.onChange {
+		fn()
+	}
+
+	return nil
+}
+
+func (s *resourceSchemaSource) resolveName() (string, error) {
+	if s.cfg.ResourceSchemaName != "" {
+		return s.cfg.ResourceSchemaName, nil
+	}
+
+	obj, exists, err := s.exports.GetStore().GetByKey(s.cfg.ResourceAPIExportName)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		return "", fmt.Errorf("apiexport %s not found in %s", s.cfg.ResourceAPIExportName, s.cfg.ResourceSchemaWorkspace)
+	}
+
+	return schemaNameFromAPIExport(obj.(*unstructured.Unstructured), storage.ContentConfigurationGVR.GroupResource())
+}
+
+// schemaNameFromAPIExport returns the name of the APIResourceSchema the export
+// currently references for the given resource.
+func schemaNameFromAPIExport(export *unstructured.Unstructured, gr schema.GroupResource) (string, error) {
+	resources, _, err := unstructured.NestedSlice(export.Object, "spec", "resources")
+	if err != nil {
+		return "", fmt.Errorf("failed to read resources of apiexport %s: %w", export.GetName(), err)
+	}
+
+	for _, r := range resources {
+		resource, ok := r.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		name, _, _ := unstructured.NestedString(resource, "name")
+		group, _, _ := unstructured.NestedString(resource, "group")
+		if name != gr.Resource || group != gr.Group {
+			continue
+		}
+
+		schemaName, _, _ := unstructured.NestedString(resource, "schema")
+		if schemaName == "" {
+			return "", fmt.Errorf("apiexport %s has no schema for %s", export.GetName(), gr)
+		}
+		return schemaName, nil
+	}
+
+	return "", fmt.Errorf("apiexport %s does not export %s", export.GetName(), gr)
+}
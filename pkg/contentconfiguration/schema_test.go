@@ -0,0 +1,182 @@
+package contentconfiguration
+
+import (
+	"testing"
+
+	"github.com/platform-mesh/virtual-workspaces/pkg/config"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestSchemaNameFromAPIExport(t *testing.T) {
+	t.Parallel()
+
+	gr := schema.GroupResource{Group: "ui.platform-mesh.io", Resource: "contentconfigurations"}
+
+	newExport := func(resources ...any) *unstructured.Unstructured {
+		export := &unstructured.Unstructured{Object: map[string]any{}}
+		export.SetName("ui.platform-mesh.io")
+		if resources != nil {
+			_ = unstructured.SetNestedSlice(export.Object, resources, "spec", "resources")
+		}
+		return export
+	}
+
+	tests := []struct {
+		name    string
+		export  *unstructured.Unstructured
+		want    string
+		wantErr string
+	}{
+		{
+			name: "finds schema of the resource",
+			export: newExport(
+				map[string]any{"name": "other", "group": "ui.platform-mesh.io", "schema": "v1.other.ui.platform-mesh.io"},
+				map[string]any{"name": "contentconfigurations", "group": "ui.platform-mesh.io", "schema": "v261019-abc.contentconfigurations.ui.platform-mesh.io"},
+			),
+			want: "v261019-abc.contentconfigurations.ui.platform-mesh.io",
+		},
+		{
+			name: "ignores the resource in other groups",
+			export: newExport(
+				map[string]any{"name": "contentconfigurations", "group": "other.io", "schema": "v1.contentconfigurations.other.io"},
+			),
+			wantErr: "does not export contentconfigurations.ui.platform-mesh.io",
+		},
+		{
+			name:    "fails without resources",
+			export:  newExport(),
+			wantErr: "does not export contentconfigurations.ui.platform-mesh.io",
+		},
+		{
+			name: "fails without schema name",
+			export: newExport(
+				map[string]any{"name": "contentconfigurations", "group": "ui.platform-mesh.io"},
+			),
+			wantErr: "has no schema",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := schemaNameFromAPIExport(tt.export, gr)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func newTestResourceSchema(name, resourceVersion string) *unstructured.Unstructured {
+	resourceSchema := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "apis.kcp.io/v1alpha1",
+		"kind":       "APIResourceSchema",
+	}}
+	resourceSchema.SetName(name)
+	resourceSchema.SetUID(types.UID(name))
+	resourceSchema.SetResourceVersion(resourceVersion)
+	return resourceSchema
+}
+
+func newTestSchemaSource(t *testing.T, cfg config.ServiceConfig) (*resourceSchemaSource, *int) {
+	t.Helper()
+
+	newInformer := func() cache.SharedIndexInformer {
+		return cache.NewSharedIndexInformer(&cache.ListWatch{}, &unstructured.Unstructured{}, 0, cache.Indexers{})
+	}
+
+	s := &resourceSchemaSource{cfg: cfg, schemas: newInformer()}
+	if cfg.ResourceSchemaName == "" {
+		s.exports = newInformer()
+	}
+
+	changes := 0
+	s.OnChange(func() { changes++ })
+	return s, &changes
+}
+
+func TestResourceSchemaSourceSync(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.NewServiceConfig()
+
+	t.Run("serves the schema referenced by the apiexport", func(t *testing.T) {
+		t.Parallel()
+
+		s, changes := newTestSchemaSource(t, cfg)
+		export := newExportWithSchema(cfg.ResourceAPIExportName, "v1.contentconfigurations.ui.platform-mesh.io")
+		require.NoError(t, s.exports.GetStore().Add(export))
+		require.NoError(t, s.schemas.GetStore().Add(newTestResourceSchema("v1.contentconfigurations.ui.platform-mesh.io", "1")))
+		require.NoError(t, s.schemas.GetStore().Add(newTestResourceSchema("v2.contentconfigurations.ui.platform-mesh.io", "1")))
+
+		require.NoError(t, s.sync())
+		require.Equal(t, "v1.contentconfigurations.ui.platform-mesh.io", s.Get().Name)
+		require.Equal(t, 1, *changes)
+
+		// unrelated events do not rebuild
+		require.NoError(t, s.sync())
+		require.Equal(t, 1, *changes)
+
+		// a new schema version referenced by the export is picked up
+		require.NoError(t, s.exports.GetStore().Update(newExportWithSchema(cfg.ResourceAPIExportName, "v2.contentconfigurations.ui.platform-mesh.io")))
+		require.NoError(t, s.sync())
+		require.Equal(t, "v2.contentconfigurations.ui.platform-mesh.io", s.Get().Name)
+		require.Equal(t, 2, *changes)
+	})
+
+	t.Run("picks up changes of the served schema", func(t *testing.T) {
+		t.Parallel()
+
+		pinned := cfg
+		pinned.ResourceSchemaName = "v1.contentconfigurations.ui.platform-mesh.io"
+
+		s, changes := newTestSchemaSource(t, pinned)
+		require.NoError(t, s.schemas.GetStore().Add(newTestResourceSchema(pinned.ResourceSchemaName, "1")))
+		require.NoError(t, s.sync())
+
+		require.NoError(t, s.schemas.GetStore().Update(newTestResourceSchema(pinned.ResourceSchemaName, "2")))
+		require.NoError(t, s.sync())
+		require.Equal(t, "2", s.Get().ResourceVersion)
+		require.Equal(t, 2, *changes)
+	})
+
+	t.Run("keeps the served schema if the new one is missing", func(t *testing.T) {
+		t.Parallel()
+
+		s, changes := newTestSchemaSource(t, cfg)
+		require.NoError(t, s.exports.GetStore().Add(newExportWithSchema(cfg.ResourceAPIExportName, "v1.contentconfigurations.ui.platform-mesh.io")))
+		require.NoError(t, s.schemas.GetStore().Add(newTestResourceSchema("v1.contentconfigurations.ui.platform-mesh.io", "1")))
+		require.NoError(t, s.sync())
+
+		require.NoError(t, s.exports.GetStore().Update(newExportWithSchema(cfg.ResourceAPIExportName, "v3.contentconfigurations.ui.platform-mesh.io")))
+		require.ErrorContains(t, s.sync(), "apiresourceschema v3.contentconfigurations.ui.platform-mesh.io not found")
+		require.Equal(t, "v1.contentconfigurations.ui.platform-mesh.io", s.Get().Name)
+		require.Equal(t, 1, *changes)
+	})
+
+	t.Run("fails without apiexport", func(t *testing.T) {
+		t.Parallel()
+
+		s, _ := newTestSchemaSource(t, cfg)
+		require.ErrorContains(t, s.sync(), "apiexport ui.platform-mesh.io not found")
+		require.Nil(t, s.Get())
+	})
+}
+
+func newExportWithSchema(name, schemaName string) *unstructured.Unstructured {
+	export := &unstructured.Unstructured{Object: map[string]any{}}
+	export.SetName(name)
+	_ = unstructured.SetNestedSlice(export.Object, []any{
+		map[string]any{"name": "contentconfigurations", "group": "ui.platform-mesh.io", "schema": schemaName},
+	}, "spec", "resources")
+	return export
+}
@@ -2,16 +2,24 @@ package contentconfiguration
 
 import (
 	"context"
+	"fmt"
+	"math"
 	"path"
+	"sync"
+	"time"
 
 	"github.com/kcp-dev/client-go/dynamic"
+	kcpkubernetesclientset "github.com/kcp-dev/client-go/kubernetes"
+	"github.com/kcp-dev/kcp/pkg/authorization/delegated"
 	"github.com/kcp-dev/logicalcluster/v3"
 	apisv1alpha1 "github.com/kcp-dev/sdk/apis/apis/v1alpha1"
 	kcpclientset "github.com/kcp-dev/sdk/client/clientset/versioned/cluster"
 	"github.com/kcp-dev/virtual-workspace-framework/framework"
 	virtualworkspacesdynamic "github.com/kcp-dev/virtual-workspace-framework/pkg/dynamic"
 	kcpapidefinition "github.com/kcp-dev/virtual-workspace-framework/pkg/dynamic/apidefinition"
+	"github.com/kcp-dev/virtual-workspace-framework/pkg/forwardingregistry"
 	virtualrootapiserver "github.com/kcp-dev/virtual-workspace-framework/pkg/rootapiserver"
+	"github.com/platform-mesh/virtual-workspaces/config/resources"
 	"github.com/platform-mesh/virtual-workspaces/pkg/apidefinition"
 	"github.com/platform-mesh/virtual-workspaces/pkg/authorization"
 	"github.com/platform-mesh/virtual-workspaces/pkg/config"
@@ -19,11 +27,9 @@ import (
 	"github.com/platform-mesh/virtual-workspaces/pkg/proxy"
 	"github.com/platform-mesh/virtual-workspaces/pkg/storage"
 
-	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/runtime/schema"
-	"k8s.io/apimachinery/pkg/util/sets"
-	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/klog/v2"
 
 	genericapiserver "k8s.io/apiserver/pkg/server"
 )
@@ -38,61 +44,183 @@ func BuildVirtualWorkspace(
 	ctx context.Context,
 	cfg config.ServiceConfig,
 	dynamicClient dynamic.ClusterInterface,
+	userClient forwardingregistry.DynamicClusterClientFunc,
 	kcpClusterClient kcpclientset.ClusterInterface,
+	kubeClusterClient kcpkubernetesclientset.ClusterInterface,
 	virtualWorkspaceBaseURL string,
+	contentCache storage.ContentConfigurationCache,
 ) virtualrootapiserver.NamedVirtualWorkspace {
 
 	clusterResolver := proxy.NewClusterResolver(kcpClusterClient)
+	apis := apidefinition.NewDeferredProvider()
+
+	var readClient forwardingregistry.DynamicClusterClientFunc
+	if cfg.ImpersonateWorkspaceReads {
+		readClient = userClient
+	}
+	limiter := storage.NewTenantLimiter(cfg)
 
 	return virtualrootapiserver.NamedVirtualWorkspace{
 		Name: Name,
 		VirtualWorkspace: &virtualworkspacesdynamic.DynamicVirtualWorkspace{
 			RootPathResolver: vwspath.NewPathResolver(clusterResolver, virtualWorkspaceBaseURL),
-			Authorizer: authorization.NewAttributesKeeper(
-				authorizer.AuthorizerFunc(func(ctx context.Context, a authorizer.Attributes) (authorizer.Decision, string, error) {
-					isAuthenticated := sets.New(a.GetUser().GetGroups()...).Has("system:authenticated")
-					if isAuthenticated {
-						return authorizer.DecisionAllow, "user is authenticated", nil
-					}
-
-					return authorizer.DecisionDeny, "user is not authenticated", nil
-				}), // TODO: we can think of a bit more complex authorization logic, e.g. doing some SAR, for now it is better than nothing
-			),
-			ReadyChecker: framework.ReadyFunc(func() error { return nil }),
+			Authorizer: storage.NewAuditingAuthorizer(authorization.NewAttributesKeeper(
+				authorization.NewSubjectAccessReviewAuthorizer(
+					kubeClusterClient,
+					authorization.RemapResource(storage.ContentConfigurationGVR.Group, storage.ContentConfigurationGVR.Resource),
+					delegated.Options{
+						AllowCacheTTL: cfg.AuthorizationAllowCacheTTL,
+						DenyCacheTTL:  cfg.AuthorizationDenyCacheTTL,
+					},
+				),
+			)),
+			ReadyChecker: framework.ReadyFunc(apis.Ready),
 			BootstrapAPISetManagement: func(mainConfig genericapiserver.CompletedConfig) (kcpapidefinition.APIDefinitionSetGetter, error) {
-				rawResourceSchema, err := dynamicClient.Cluster(logicalcluster.NewPath(cfg.ResourceSchemaWorkspace)).Resource(schema.GroupVersionResource{
-					Group:    "apis.kcp.io",
-					Version:  "v1alpha1",
-					Resource: "apiresourceschemas",
-				}).Get(ctx, cfg.ResourceSchemaName, v1.GetOptions{})
-				if err != nil {
-					return nil, err
-				}
-
-				var resourceSchema apisv1alpha1.APIResourceSchema
-				err = runtime.DefaultUnstructuredConverter.FromUnstructured(rawResourceSchema.Object, &resourceSchema)
+				var navigationSchema apisv1alpha1.APIResourceSchema
+				err := yaml.Unmarshal([]byte(resources.NavigationResourceSchema), &navigationSchema)
 				if err != nil {
 					return nil, err
 				}
 
-				providerWSCluster, err := clusterResolver(ctx, logicalcluster.NewPath(cfg.ResourceSchemaWorkspace))
+				var diagnosticsSchema apisv1alpha1.APIResourceSchema
+				err = yaml.Unmarshal([]byte(resources.DiagnosticsResourceSchema), &diagnosticsSchema)
 				if err != nil {
 					return nil, err
 				}
 
-				storeageProvider := storage.CreateStorageProviderFunc(
-					dynamicClient,
-					storage.ContentConfigurationLookup(dynamicClient, cfg, providerWSCluster.Name.String()),
+				// The definition set is rebuilt whenever a different schema is
+				// served. The provider is built holding definitionsLock, so
+				// changes before it exists are covered by building it from the
+				// latest schema, and changes while it is built wait for it.
+				var (
+					definitionsLock sync.Mutex
+					definitions     *apidefinition.MultiResourceProvider
 				)
+				resourceSchema := newResourceSchemaSource(dynamicClient, cfg)
+				resourceSchema.OnChange(func() {
+					definitionsLock.Lock()
+					defer definitionsLock.Unlock()
 
-				gvr := schema.GroupVersionResource{
-					Group:    "ui.platform-mesh.io",
-					Version:  "v1alpha1",
-					Resource: "contentconfigurations",
-				}
+					if definitions == nil {
+						return
+					}
+					if err := definitions.Rebuild(); err != nil {
+						klog.ErrorS(err, "failed to rebuild contentconfigurations api definitions")
+					}
+				})
+
+				// The schema and the provider workspace might not exist yet on a
+				// fresh install, so they are resolved in the background and the
+				// virtual workspace reports not ready until then.
+				go func() {
+					if err := resourceSchema.Start(ctx); err != nil {
+						apis.SetError(err)
+						return
+					}
+
+					retryWithBackoff(ctx, apis.SetError, func() error {
+						if err := resourceSchema.sync(); err != nil {
+							return err
+						}
 
-				return apidefinition.NewSingleResourceProvider(mainConfig, gvr, &resourceSchema, storeageProvider), nil
+						var providers []storage.ProviderWorkspace
+						for _, provider := range cfg.ProviderWorkspaceRules() {
+							providerPath := logicalcluster.NewPath(provider.Path)
+							providerWSCluster, err := clusterResolver(ctx, providerPath)
+							if err != nil {
+								return fmt.Errorf("failed to resolve provider workspace %s: %w", provider.Path, err)
+							}
+							providers = append(providers, storage.ProviderWorkspace{
+								Path:        providerPath,
+								Cluster:     providerWSCluster.Name,
+								EntityLabel: provider.EntityLabel,
+							})
+						}
+
+						contentConfigurationLister := storage.ContentConfigurationLister(dynamicClient, readClient, cfg, providers, contentCache)
+
+						storeageProvider := storage.CreateStorageProviderFunc(
+							dynamicClient,
+							readClient,
+							storage.ContentConfigurationLookup(dynamicClient, readClient, cfg, providers, contentCache),
+							limiter.Wrapper(),
+						)
+						if cfg.EnableContentConfigurationWrites {
+							storeageProvider = storage.CreateWritableStorageProviderFunc(
+								dynamicClient,
+								readClient,
+								&storage.Writer{
+									ClientFunc:      userClient,
+									Projected:       storage.ProjectedContentConfiguration(dynamicClient, cfg, providers, contentCache),
+									ContentForLabel: cfg.ContentForLabel,
+									Limiter:         limiter,
+								},
+								storage.ContentConfigurationLookup(dynamicClient, readClient, cfg, providers, contentCache),
+								limiter.Wrapper(),
+							)
+						}
+
+						navigationStorageProvider := storage.CreateNavigationStorageProviderFunc(limiter.Lister(contentConfigurationLister))
+						diagnosticsStorageProvider := storage.CreateDiagnosticsStorageProviderFunc(limiter.Lister(contentConfigurationLister))
+
+						definitionsLock.Lock()
+						defer definitionsLock.Unlock()
+
+						provider, err := apidefinition.NewMultiResourceProvider(ctx, mainConfig,
+							apidefinition.Resource{
+								Schema:          resourceSchema.Get,
+								StorageProvider: storeageProvider,
+							},
+							apidefinition.Resource{
+								Schema:          apidefinition.StaticSchema(&navigationSchema),
+								StorageProvider: navigationStorageProvider,
+							},
+							apidefinition.Resource{
+								Schema:          apidefinition.StaticSchema(&diagnosticsSchema),
+								StorageProvider: diagnosticsStorageProvider,
+							},
+						)
+						if err != nil {
+							return err
+						}
+						definitions = provider
+						apis.Set(provider)
+
+						klog.InfoS("serving contentconfigurations virtual workspace")
+						return nil
+					})
+				}()
+
+				return apis, nil
 			},
 		},
 	}
 }
+
+// retryWithBackoff calls fn until it succeeds or ctx is done, backing off
+// exponentially between attempts. Failures are passed to onError.
+func retryWithBackoff(ctx context.Context, onError func(error), fn func() error) {
+	backoff := wait.Backoff{
+		Duration: time.Second,
+		Factor:   2,
+		Jitter:   0.1,
+		Steps:    math.MaxInt32,
+		Cap:      time.Minute,
+	}
+
+	for {
+		err := fn()
+		if err == nil {
+			return
+		}
+
+		klog.ErrorS(err, "contentconfigurations virtual workspace is not ready, retrying")
+		onError(err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff.Step()):
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package marketplace
+
+import (
+	"context"
+	"path"
+
+	"github.com/kcp-dev/multicluster-provider/apiexport"
+	apisv1alpha1 "github.com/kcp-dev/sdk/apis/apis/v1alpha1"
+	"github.com/kcp-dev/virtual-workspace-framework/framework"
+	virtualworkspacesdynamic "github.com/kcp-dev/virtual-workspace-framework/pkg/dynamic"
+	kcpapidefinition "github.com/kcp-dev/virtual-workspace-framework/pkg/dynamic/apidefinition"
+	virtualrootapiserver "github.com/kcp-dev/virtual-workspace-framework/pkg/rootapiserver"
+	"github.com/platform-mesh/virtual-workspaces/api/v1alpha1"
+	"github.com/platform-mesh/virtual-workspaces/config/resources"
+	"github.com/platform-mesh/virtual-workspaces/pkg/apidefinition"
+	"github.com/platform-mesh/virtual-workspaces/pkg/authorization"
+	"github.com/platform-mesh/virtual-workspaces/pkg/config"
+	vwspath "github.com/platform-mesh/virtual-workspaces/pkg/path"
+	"github.com/platform-mesh/virtual-workspaces/pkg/storage"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/yaml"
+
+	genericapiserver "k8s.io/apiserver/pkg/server"
+)
+
+var CatalogName = "marketplace-catalog"
+
+func CatalogVirtualWorkspaceBaseURL() string {
+	return path.Join("/services", "marketplace", "catalog")
+}
+
+// BuildCatalogVirtualWorkspace serves the marketplace entries of all providers
+// to anonymous users, without a workspace in the URL and without installed
+// state. Requests are not rate limited per tenant, as the catalog is served
+// from the provider cache and all anonymous users would share a single tenant.
+func BuildCatalogVirtualWorkspace(
+	ctx context.Context,
+	cfg config.ServiceConfig,
+	virtualWorkspaceBaseURL string,
+	provider *apiexport.Provider,
+) virtualrootapiserver.NamedVirtualWorkspace {
+	return virtualrootapiserver.NamedVirtualWorkspace{
+		Name: CatalogName,
+		VirtualWorkspace: &virtualworkspacesdynamic.DynamicVirtualWorkspace{
+			RootPathResolver: vwspath.NewCatalogPathResolver(virtualWorkspaceBaseURL),
+			Authorizer: authorization.NewAttributesKeeper(
+				authorization.NewCatalogAuthorizer(v1alpha1.GroupVersion.Group, "marketplaceentries"),
+			),
+			ReadyChecker: framework.ReadyFunc(func() error { return nil }),
+			BootstrapAPISetManagement: func(mainConfig genericapiserver.CompletedConfig) (kcpapidefinition.APIDefinitionSetGetter, error) {
+
+				var resourceSchema apisv1alpha1.APIResourceSchema
+				err := yaml.Unmarshal([]byte(resources.ResourceSchema), &resourceSchema)
+				if err != nil {
+					return nil, err
+				}
+
+				gvr := schema.GroupVersionResource{
+					Group:    resourceSchema.Spec.Group,
+					Version:  resourceSchema.Spec.Versions[0].Name,
+					Resource: resourceSchema.Spec.Names.Plural,
+				}
+
+				storageProvider := storage.CreateCatalogStorageProviderFunc(storage.MarketplaceCatalog(provider, cfg))
+
+				return apidefinition.NewSingleResourceProvider(mainConfig, gvr, &resourceSchema, storageProvider), nil
+			},
+		},
+	}
+}
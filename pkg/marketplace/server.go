@@ -5,12 +5,15 @@ import (
 	"path"
 
 	"github.com/kcp-dev/client-go/dynamic"
+	kcpkubernetesclientset "github.com/kcp-dev/client-go/kubernetes"
+	"github.com/kcp-dev/kcp/pkg/authorization/delegated"
 	"github.com/kcp-dev/multicluster-provider/apiexport"
 	apisv1alpha1 "github.com/kcp-dev/sdk/apis/apis/v1alpha1"
 	kcpclientset "github.com/kcp-dev/sdk/client/clientset/versioned/cluster"
 	"github.com/kcp-dev/virtual-workspace-framework/framework"
 	virtualworkspacesdynamic "github.com/kcp-dev/virtual-workspace-framework/pkg/dynamic"
 	kcpapidefinition "github.com/kcp-dev/virtual-workspace-framework/pkg/dynamic/apidefinition"
+	"github.com/kcp-dev/virtual-workspace-framework/pkg/forwardingregistry"
 	virtualrootapiserver "github.com/kcp-dev/virtual-workspace-framework/pkg/rootapiserver"
 	"github.com/platform-mesh/virtual-workspaces/config/resources"
 	"github.com/platform-mesh/virtual-workspaces/pkg/apidefinition"
@@ -21,9 +24,7 @@ import (
 	"github.com/platform-mesh/virtual-workspaces/pkg/storage"
 
 	"k8s.io/apimachinery/pkg/runtime/schema"
-	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/yaml"
-	"k8s.io/apiserver/pkg/authorization/authorizer"
 
 	genericapiserver "k8s.io/apiserver/pkg/server"
 )
@@ -38,27 +39,35 @@ func BuildVirtualWorkspace(
 	ctx context.Context,
 	cfg config.ServiceConfig,
 	dynamicClient dynamic.ClusterInterface,
+	userClient forwardingregistry.DynamicClusterClientFunc,
 	kcpClusterClient kcpclientset.ClusterInterface,
+	kubeClusterClient kcpkubernetesclientset.ClusterInterface,
 	virtualWorkspaceBaseURL string,
 	provider *apiexport.Provider,
 ) virtualrootapiserver.NamedVirtualWorkspace {
 
 	clusterResolver := proxy.NewClusterResolver(kcpClusterClient)
+	authorizationCacheOptions := delegated.Options{
+		AllowCacheTTL: cfg.AuthorizationAllowCacheTTL,
+		DenyCacheTTL:  cfg.AuthorizationDenyCacheTTL,
+	}
+
+	var readClient forwardingregistry.DynamicClusterClientFunc
+	if cfg.ImpersonateWorkspaceReads {
+		readClient = userClient
+	}
 
 	return virtualrootapiserver.NamedVirtualWorkspace{
 		Name: Name,
 		VirtualWorkspace: &virtualworkspacesdynamic.DynamicVirtualWorkspace{
 			RootPathResolver: vwspath.NewPathResolver(clusterResolver, virtualWorkspaceBaseURL),
-			Authorizer: authorization.NewAttributesKeeper(
-				authorizer.AuthorizerFunc(func(ctx context.Context, a authorizer.Attributes) (authorizer.Decision, string, error) {
-					isAuthenticated := sets.New(a.GetUser().GetGroups()...).Has("system:authenticated")
-					if isAuthenticated {
-						return authorizer.DecisionAllow, "user is authenticated", nil
-					}
-
-					return authorizer.DecisionDeny, "user is not authenticated", nil
-				}), // TODO: we can think of a bit more complex authorization logic, e.g. doing some SAR, for now it is better than nothing
-			),
+			Authorizer: storage.NewAuditingAuthorizer(authorization.NewAttributesKeeper(
+				authorization.NewSubjectAccessReviewAuthorizer(
+					kubeClusterClient,
+					authorization.RemapResource(apisv1alpha1.SchemeGroupVersion.Group, "apibindings"),
+					authorizationCacheOptions,
+				),
+			)),
 			ReadyChecker: framework.ReadyFunc(func() error { return nil }),
 			BootstrapAPISetManagement: func(mainConfig genericapiserver.CompletedConfig) (kcpapidefinition.APIDefinitionSetGetter, error) {
 
@@ -68,11 +77,13 @@ func BuildVirtualWorkspace(
 					return nil, err
 				}
 
-				marketplaceFilter := storage.Marketplace(provider, cfg)
+				marketplaceFilter := storage.Marketplace(provider, readClient, cfg, authorization.NewWorkspaceAuthorizers(kubeClusterClient, authorizationCacheOptions))
 
 				storeageProvider := storage.CreateStorageProviderFunc(
 					dynamicClient,
+					nil,
 					marketplaceFilter,
+					storage.NewTenantLimiter(cfg).Wrapper(),
 				)
 
 				gvr := schema.GroupVersionResource{
@@ -77,3 +77,16 @@ func NewPathResolver(clusterResolver proxy.ClusterResolver, virtualWorkspaceBase
 		return true, strings.TrimSuffix(urlPath, realPath), completedContext
 	}
 }
+
+// NewCatalogPathResolver accepts requests below virtualWorkspaceBaseURL without
+// a workspace, e.g. /services/marketplace/catalog/apis/…, for virtual
+// workspaces that serve no workspace data.
+func NewCatalogPathResolver(virtualWorkspaceBaseURL string) framework.RootPathResolverFunc {
+	return func(urlPath string, requestContext context.Context) (accepted bool, prefixToStrip string, completedContext context.Context) {
+		if urlPath != virtualWorkspaceBaseURL && !strings.HasPrefix(urlPath, virtualWorkspaceBaseURL+"/") {
+			return false, "", requestContext
+		}
+
+		return true, virtualWorkspaceBaseURL, authorization.WithAttributeHolder(requestContext)
+	}
+}
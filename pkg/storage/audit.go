@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apiserver/pkg/audit"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+)
+
+// Audit annotations added to the events of virtual workspace requests. User,
+// verb, resource and the authorization decision are part of every event already.
+const (
+	// AuditClusterPathAnnotation is the workspace path as requested in the URL.
+	AuditClusterPathAnnotation = "virtual-workspaces.platform-mesh.io/cluster-path"
+	// AuditLogicalClusterAnnotation is the logical cluster the path resolved to.
+	AuditLogicalClusterAnnotation = "virtual-workspaces.platform-mesh.io/logical-cluster"
+	// AuditSourcesAnnotation lists the sources read to serve the response, as
+	// comma separated <kind>/<name>[@<logical cluster>].
+	AuditSourcesAnnotation = "virtual-workspaces.platform-mesh.io/sources"
+)
+
+// NewAuditingAuthorizer annotates the audit event of a request with the
+// requested workspace before delegating. It runs as part of the authorization
+// filter, as the path resolver runs before the audit context exists.
+func NewAuditingAuthorizer(delegate authorizer.Authorizer) authorizer.Authorizer {
+	return authorizer.AuthorizerFunc(func(ctx context.Context, a authorizer.Attributes) (authorizer.Decision, string, error) {
+		if path, ok := ClusterPathFrom(ctx); ok {
+			audit.AddAuditAnnotation(ctx, AuditClusterPathAnnotation, path.String())
+		}
+		if cluster := genericapirequest.ClusterFrom(ctx); cluster != nil && !cluster.Name.Empty() {
+			audit.AddAuditAnnotation(ctx, AuditLogicalClusterAnnotation, cluster.Name.String())
+		}
+
+		return delegate.Authorize(ctx, a)
+	})
+}
+
+// auditSources records the sources of a list response in its audit event.
+func auditSources(ctx context.Context, sources []contentSource) {
+	names := make([]string, 0, len(sources))
+	for _, source := range sources {
+		name := fmt.Sprintf("%s/%s", source.kind, source.name)
+		if !source.cluster.Empty() {
+			name += "@" + source.cluster.String()
+		}
+		names = append(names, name)
+	}
+
+	audit.AddAuditAnnotation(ctx, AuditSourcesAnnotation, strings.Join(names, ","))
+}
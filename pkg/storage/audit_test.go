@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+	"github.com/kcp-dev/virtual-workspace-framework/pkg/forwardingregistry"
+	"github.com/platform-mesh/virtual-workspaces/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/apis/meta/internalversion"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	auditinternal "k8s.io/apiserver/pkg/apis/audit"
+	"k8s.io/apiserver/pkg/audit"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+)
+
+func withAuditContext(t *testing.T, ctx context.Context) context.Context {
+	ctx = audit.WithAuditContext(ctx)
+	require.NoError(t, audit.AuditContextFrom(ctx).Init(audit.RequestAuditConfig{Level: auditinternal.LevelMetadata}, nil))
+	return ctx
+}
+
+func TestAuditingAuthorizer(t *testing.T) {
+	t.Parallel()
+
+	delegate := authorizer.AuthorizerFunc(func(ctx context.Context, a authorizer.Attributes) (authorizer.Decision, string, error) {
+		return authorizer.DecisionAllow, "", nil
+	})
+
+	ctx := withAuditContext(t, context.Background())
+	ctx = WithClusterPath(ctx, logicalcluster.NewPath("root:orgs:my-org"))
+	ctx = genericapirequest.WithCluster(ctx, genericapirequest.Cluster{Name: "2x8z5k"})
+
+	decision, _, err := NewAuditingAuthorizer(delegate).Authorize(ctx, authorizer.AttributesRecord{
+		User: &user.DefaultInfo{Name: "alice"},
+		Verb: "list",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, authorizer.DecisionAllow, decision)
+
+	annotations := audit.AuditContextFrom(ctx).GetEventAnnotations()
+	assert.Equal(t, "root:orgs:my-org", annotations[AuditClusterPathAnnotation])
+	assert.Equal(t, "2x8z5k", annotations[AuditLogicalClusterAnnotation])
+}
+
+func TestContentConfigurationLookup_AuditsSources(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.NewServiceConfig()
+	accountPath := logicalcluster.NewPath("root:orgs:my-org:my-account")
+	accountCluster := logicalcluster.Name("my-account")
+
+	storage := &forwardingregistry.StoreFuncs{}
+	storage.ListerFunc = multiClusterLister(map[logicalcluster.Name][]unstructured.Unstructured{})
+
+	client := &fakeDynamicClusterClient{
+		objects: map[logicalcluster.Path]map[string][]unstructured.Unstructured{
+			accountPath: {"apibindings": {newAPIBinding("openmcp", "openmcp.cloud", "export-ws")}},
+		},
+	}
+
+	wrapper := ContentConfigurationLookup(client, nil, cfg, testProviders(cfg, "provider-ws"), nil)
+	wrapper.Decorate(schema.GroupResource{Group: "ui.platform-mesh.io", Resource: "contentconfigurations"}, storage)
+
+	ctx := withAuditContext(t, context.Background())
+	ctx = WithClusterPath(ctx, accountPath)
+	ctx = genericapirequest.WithCluster(ctx, genericapirequest.Cluster{Name: accountCluster})
+
+	_, err := storage.List(ctx, &internalversion.ListOptions{})
+	require.NoError(t, err)
+
+	annotations := audit.AuditContextFrom(ctx).GetEventAnnotations()
+	assert.Equal(t,
+		"local/root:orgs:my-org:my-account@my-account,apiexport/openmcp.cloud@export-ws,provider/provider-ws@provider-ws",
+		annotations[AuditSourcesAnnotation],
+	)
+}
@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"fmt"
+
+	apisv1alpha1 "github.com/kcp-dev/sdk/apis/apis/v1alpha1"
+	conditionsv1alpha1 "github.com/kcp-dev/sdk/apis/third_party/conditions/apis/conditions/v1alpha1"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// InactiveAnnotation marks contentconfigurations from APIBindings that are
+	// not bound. It is only set when inactive bindings are included for diagnostics.
+	InactiveAnnotation = "ui.platform-mesh.io/inactive"
+	// InactiveReasonAnnotation holds why the APIBinding is considered inactive.
+	InactiveReasonAnnotation = "ui.platform-mesh.io/inactive-reason"
+)
+
+// bindingReadinessConditions must not be False for an APIBinding to be active.
+// Missing conditions are tolerated, as not every kcp version sets all of them.
+var bindingReadinessConditions = []conditionsv1alpha1.ConditionType{
+	conditionsv1alpha1.ReadyCondition,
+	apisv1alpha1.APIExportValid,
+	apisv1alpha1.InitialBindingCompleted,
+	apisv1alpha1.PermissionClaimsValid,
+	apisv1alpha1.PermissionClaimsApplied,
+}
+
+// apiBindingInactiveReason returns why the APIBinding's APIs are not usable in
+// the workspace, or an empty string if the binding is bound and ready.
+func apiBindingInactiveReason(binding *unstructured.Unstructured) string {
+	phase, _, _ := unstructured.NestedString(binding.Object, "status", "phase")
+	if phase != string(apisv1alpha1.APIBindingPhaseBound) {
+		if phase == "" {
+			return "binding has no phase yet"
+		}
+		return fmt.Sprintf("binding is in phase %s", phase)
+	}
+
+	conditions, _, _ := unstructured.NestedSlice(binding.Object, "status", "conditions")
+	for _, required := range bindingReadinessConditions {
+		for _, raw := range conditions {
+			condition, ok := raw.(map[string]interface{})
+			if !ok || condition["type"] != string(required) {
+				continue
+			}
+			if condition["status"] == "False" {
+				return fmt.Sprintf("condition %s is False", required)
+			}
+		}
+	}
+
+	return ""
+}
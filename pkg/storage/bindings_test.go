@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestAPIBindingInactiveReason(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		phase      string
+		conditions []interface{}
+		expected   string
+	}{
+		{
+			name:     "bound without conditions is active",
+			phase:    "Bound",
+			expected: "",
+		},
+		{
+			name:  "bound with ready conditions is active",
+			phase: "Bound",
+			conditions: []interface{}{
+				map[string]interface{}{"type": "Ready", "status": "True"},
+				map[string]interface{}{"type": "PermissionClaimsApplied", "status": "True"},
+			},
+			expected: "",
+		},
+		{
+			name:     "binding without phase is inactive",
+			expected: "binding has no phase yet",
+		},
+		{
+			name:     "binding in phase Binding is inactive",
+			phase:    "Binding",
+			expected: "binding is in phase Binding",
+		},
+		{
+			name:  "unaccepted permission claims are inactive",
+			phase: "Bound",
+			conditions: []interface{}{
+				map[string]interface{}{"type": "Ready", "status": "True"},
+				map[string]interface{}{"type": "PermissionClaimsValid", "status": "False"},
+			},
+			expected: "condition PermissionClaimsValid is False",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			binding := newAPIBinding("binding", "export", "export-ws")
+			unstructured.RemoveNestedField(binding.Object, "status", "phase")
+			if tt.phase != "" {
+				require.NoError(t, unstructured.SetNestedField(binding.Object, tt.phase, "status", "phase"))
+			}
+			if tt.conditions != nil {
+				require.NoError(t, unstructured.SetNestedSlice(binding.Object, tt.conditions, "status", "conditions"))
+			}
+
+			assert.Equal(t, tt.expected, apiBindingInactiveReason(&binding))
+		})
+	}
+}
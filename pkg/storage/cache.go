@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+	"github.com/kcp-dev/multicluster-provider/apiexport"
+	"github.com/kcp-dev/virtual-workspace-framework/pkg/forwardingregistry"
+	"github.com/platform-mesh/virtual-workspaces/pkg/config"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/multicluster-runtime/pkg/multicluster"
+
+	"k8s.io/apimachinery/pkg/apis/meta/internalversion"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+)
+
+var contentConfigurationListGVK = ContentConfigurationGVR.GroupVersion().WithKind("ContentConfigurationList")
+
+// ContentConfigurationCache serves contentconfigurations of export and provider
+// workspaces from memory. List reports false if the given cluster is not
+// cached, in which case the caller falls back to a live request.
+type ContentConfigurationCache interface {
+	List(ctx context.Context, cluster logicalcluster.Name, selector labels.Selector) ([]unstructured.Unstructured, bool, error)
+}
+
+type providerContentConfigurationCache struct {
+	provider *apiexport.Provider
+}
+
+// NewContentConfigurationCache returns a cache backed by the informers of an
+// APIExport provider for the contentconfigurations APIExport. All workspaces
+// binding that export share a single wildcard informer per shard.
+func NewContentConfigurationCache(provider *apiexport.Provider) ContentConfigurationCache {
+	return &providerContentConfigurationCache{provider: provider}
+}
+
+// ContentConfigurationCacheSelector returns the selector every cached source
+// requires: export, ancestor and provider contentconfigurations are all
+// selected by an entity label. If provider workspaces use different entity
+// labels no single selector covers them, and everything is cached.
+func ContentConfigurationCacheSelector(cfg config.ServiceConfig) labels.Selector {
+	for _, provider := range cfg.ProviderWorkspaceRules() {
+		if provider.EntityLabel != cfg.EntityLabel {
+			klog.InfoS("provider workspaces use different entity labels, caching all contentconfigurations", "entityLabel", provider.EntityLabel)
+			return labels.Everything()
+		}
+	}
+
+	hasEntity, err := labels.NewRequirement(cfg.EntityLabel, selection.Exists, nil)
+	if err != nil {
+		klog.ErrorS(err, "invalid entity label, caching all contentconfigurations", "entityLabel", cfg.EntityLabel)
+		return labels.Everything()
+	}
+	return labels.NewSelector().Add(*hasEntity)
+}
+
+// WithContentConfigurationSelector returns a copy of restCfg whose list and
+// watch requests of contentconfigurations only return those matching
+// selector. The APIExport provider builds its informers without options, so
+// this is how its cache is kept from holding unrelated contentconfigurations.
+func WithContentConfigurationSelector(restCfg *rest.Config, selector labels.Selector) *rest.Config {
+	restCfg = rest.CopyConfig(restCfg)
+	if selector.Empty() {
+		return restCfg
+	}
+
+	restCfg.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		return &labelSelectorRoundTripper{
+			delegate: rt,
+			resource: ContentConfigurationGVR.Resource,
+			selector: selector.String(),
+		}
+	})
+	return restCfg
+}
+
+// labelSelectorRoundTripper adds selector to the collection requests of resource.
+type labelSelectorRoundTripper struct {
+	delegate http.RoundTripper
+	resource string
+	selector string
+}
+
+func (rt *labelSelectorRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || path.Base(req.URL.Path) != rt.resource {
+		return rt.delegate.RoundTrip(req)
+	}
+
+	req = req.Clone(req.Context())
+	query := req.URL.Query()
+	selector := rt.selector
+	if existing := query.Get("labelSelector"); existing != "" {
+		selector = existing + "," + selector
+	}
+	query.Set("labelSelector", selector)
+	req.URL.RawQuery = query.Encode()
+
+	return rt.delegate.RoundTrip(req)
+}
+
+func (c *providerContentConfigurationCache) List(ctx context.Context, cluster logicalcluster.Name, selector labels.Selector) ([]unstructured.Unstructured, bool, error) {
+	cl, err := c.provider.Get(ctx, multicluster.ClusterName(cluster.String()))
+	if errors.Is(err, multicluster.ErrClusterNotFound) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(contentConfigurationListGVK)
+
+	var opts []client.ListOption
+	if selector != nil {
+		opts = append(opts, client.MatchingLabelsSelector{Selector: selector})
+	}
+
+	if err := cl.GetCache().List(ctx, list, opts...); err != nil {
+		return nil, false, err
+	}
+
+	return list.Items, true, nil
+}
+
+// cachedLister serves list requests from the cache if the cluster of the
+// request is cached, and delegates to the live lister otherwise.
+func cachedLister(cache ContentConfigurationCache, delegate forwardingregistry.ListerFunc) forwardingregistry.ListerFunc {
+	if cache == nil {
+		return delegate
+	}
+
+	return func(ctx context.Context, options *internalversion.ListOptions) (runtime.Object, error) {
+		cluster := genericapirequest.ClusterFrom(ctx)
+		if cluster == nil {
+			return delegate(ctx, options)
+		}
+
+		items, ok, err := cache.List(ctx, cluster.Name, options.LabelSelector)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			klog.V(8).InfoS("contentconfigurations of cluster not cached, listing live", "cluster", cluster.Name)
+			return delegate(ctx, options)
+		}
+
+		return &unstructured.UnstructuredList{Items: items}, nil
+	}
+}
@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+	"github.com/platform-mesh/virtual-workspaces/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/apis/meta/internalversion"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/rest"
+
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+)
+
+type fakeContentConfigurationCache struct {
+	ccsByCluster map[logicalcluster.Name][]unstructured.Unstructured
+}
+
+func (f *fakeContentConfigurationCache) List(_ context.Context, cluster logicalcluster.Name, selector labels.Selector) ([]unstructured.Unstructured, bool, error) {
+	ccs, ok := f.ccsByCluster[cluster]
+	if !ok {
+		return nil, false, nil
+	}
+
+	var result []unstructured.Unstructured
+	for _, cc := range ccs {
+		if selector == nil || selector.Matches(labels.Set(cc.GetLabels())) {
+			result = append(result, cc)
+		}
+	}
+	return result, true, nil
+}
+
+func TestCachedLister(t *testing.T) {
+	t.Parallel()
+
+	cache := &fakeContentConfigurationCache{
+		ccsByCluster: map[logicalcluster.Name][]unstructured.Unstructured{
+			"cached-ws": {newCC("from-cache", map[string]string{"area": "home"}, true)},
+		},
+	}
+	live := multiClusterLister(map[logicalcluster.Name][]unstructured.Unstructured{
+		"cached-ws":   {newCC("from-live", map[string]string{"area": "home"}, true)},
+		"uncached-ws": {newCC("from-live", map[string]string{"area": "home"}, true)},
+	})
+
+	lister := cachedLister(cache, live)
+
+	tests := []struct {
+		cluster  logicalcluster.Name
+		selector labels.Selector
+		expected []string
+	}{
+		{cluster: "cached-ws", selector: labels.SelectorFromSet(labels.Set{"area": "home"}), expected: []string{"from-cache"}},
+		{cluster: "cached-ws", selector: labels.SelectorFromSet(labels.Set{"area": "settings"}), expected: nil},
+		{cluster: "uncached-ws", selector: labels.Everything(), expected: []string{"from-live"}},
+	}
+
+	for _, tt := range tests {
+		ctx := genericapirequest.WithCluster(context.Background(), genericapirequest.Cluster{Name: tt.cluster})
+
+		result, err := lister(ctx, &internalversion.ListOptions{LabelSelector: tt.selector})
+		require.NoError(t, err)
+
+		var gotNames []string
+		for _, item := range result.(*unstructured.UnstructuredList).Items {
+			gotNames = append(gotNames, item.GetName())
+		}
+		assert.Equal(t, tt.expected, gotNames, "cluster %s", tt.cluster)
+	}
+}
+
+type recordingRoundTripper struct {
+	requests []*http.Request
+}
+
+func (r *recordingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.requests = append(r.requests, req)
+	return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+}
+
+func TestWithContentConfigurationSelector(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.NewServiceConfig()
+	selector := ContentConfigurationCacheSelector(cfg)
+	require.Equal(t, cfg.EntityLabel, selector.String())
+
+	recorder := &recordingRoundTripper{}
+	restCfg := WithContentConfigurationSelector(&rest.Config{Host: "https://kcp.example.com/clusters/*"}, selector)
+	rt := restCfg.WrapTransport(recorder)
+
+	for _, target := range []string{
+		"/clusters/*/apis/ui.platform-mesh.io/v1alpha1/contentconfigurations?watch=true",
+		"/clusters/*/apis/ui.platform-mesh.io/v1alpha1/contentconfigurations?labelSelector=team%3Da",
+		"/clusters/*/apis/ui.platform-mesh.io/v1alpha1/contentconfigurations/main",
+		"/clusters/*/apis/apis.kcp.io/v1alpha1/apibindings",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "https://kcp.example.com"+target, nil)
+		_, err := rt.RoundTrip(req)
+		require.NoError(t, err)
+	}
+
+	require.Len(t, recorder.requests, 4)
+	assert.Equal(t, cfg.EntityLabel, recorder.requests[0].URL.Query().Get("labelSelector"))
+	assert.Equal(t, "true", recorder.requests[0].URL.Query().Get("watch"))
+	assert.Equal(t, "team=a,"+cfg.EntityLabel, recorder.requests[1].URL.Query().Get("labelSelector"))
+	assert.Empty(t, recorder.requests[2].URL.RawQuery)
+	assert.Empty(t, recorder.requests[3].URL.RawQuery)
+}
+
+func TestContentConfigurationCacheSelector_DifferentEntityLabels(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.NewServiceConfig()
+	cfg.ProviderWorkspaces = []string{"root:providers", "root:teams=teams.platform-mesh.io/entity"}
+
+	assert.True(t, ContentConfigurationCacheSelector(cfg).Empty())
+}
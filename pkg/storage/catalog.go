@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/kcp-dev/virtual-workspace-framework/pkg/dynamic/apiserver"
+	registry "github.com/kcp-dev/virtual-workspace-framework/pkg/forwardingregistry"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/internalversion"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// CreateCatalogStorageProviderFunc returns a storage provider for a read-only
+// resource served by lister alone, e.g. MarketplaceCatalog. Unlike the
+// forwarding storage it needs no workspace in the request: gets are answered
+// from the list and label selectors are applied to it.
+func CreateCatalogStorageProviderFunc(lister registry.ListerFunc) func(ctx context.Context) (apiserver.RestProviderFunc, error) {
+	return createReadOnlyStorageProviderFunc(func(resource schema.GroupVersionResource, kind, listKind schema.GroupVersionKind) (registry.GetterFunc, registry.ListerFunc) {
+		list := func(ctx context.Context, options *internalversion.ListOptions) (*unstructured.UnstructuredList, error) {
+			result, err := lister(ctx, options)
+			if err != nil {
+				return nil, err
+			}
+
+			ul := result.(*unstructured.UnstructuredList)
+			ul.SetGroupVersionKind(listKind)
+			return ul, nil
+		}
+
+		getter := func(ctx context.Context, name string, options *metav1.GetOptions) (runtime.Object, error) {
+			ul, err := list(ctx, &internalversion.ListOptions{})
+			if err != nil {
+				return nil, err
+			}
+			for _, item := range ul.Items {
+				if item.GetName() == name {
+					return &item, nil
+				}
+			}
+			return nil, kerrors.NewNotFound(resource.GroupResource(), name)
+		}
+		filteredLister := func(ctx context.Context, options *internalversion.ListOptions) (runtime.Object, error) {
+			ul, err := list(ctx, options)
+			if err != nil {
+				return nil, err
+			}
+
+			if options != nil && options.LabelSelector != nil && !options.LabelSelector.Empty() {
+				matching := ul.Items[:0]
+				for _, item := range ul.Items {
+					if options.LabelSelector.Matches(labels.Set(item.GetLabels())) {
+						matching = append(matching, item)
+					}
+				}
+				ul.Items = matching
+			}
+			return ul, nil
+		}
+
+		return getter, filteredLister
+	})
+}
@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+	apisv1alpha1 "github.com/kcp-dev/sdk/apis/apis/v1alpha1"
+	extensionapiv1alpha1 "github.com/platform-mesh/extension-manager-operator/api/v1alpha1"
+	"github.com/platform-mesh/virtual-workspaces/api/v1alpha1"
+	"github.com/platform-mesh/virtual-workspaces/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"k8s.io/apimachinery/pkg/apis/meta/internalversion"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// fakeProviderLister serves providers and the APIExports matching the label
+// selector of a list, like the provider cache.
+type fakeProviderLister struct {
+	providers []extensionapiv1alpha1.ProviderMetadata
+	exports   []apisv1alpha1.APIExport
+}
+
+func (f *fakeProviderLister) List(_ context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	listOpts := &client.ListOptions{}
+	listOpts.ApplyOptions(opts)
+
+	switch list := list.(type) {
+	case *extensionapiv1alpha1.ProviderMetadataList:
+		list.Items = f.providers
+	case *apisv1alpha1.APIExportList:
+		for _, export := range f.exports {
+			if listOpts.LabelSelector == nil || listOpts.LabelSelector.Matches(labels.Set(export.Labels)) {
+				list.Items = append(list.Items, export)
+			}
+		}
+	}
+	return nil
+}
+
+func TestMarketplaceCatalog(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.NewServiceConfig()
+
+	export := func(name string, ready bool) apisv1alpha1.APIExport {
+		export := apisv1alpha1.APIExport{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Labels:      map[string]string{cfg.ContentForLabel: "provider"},
+				Annotations: map[string]string{logicalcluster.AnnotationKey: "provider-ws"},
+			},
+			Status: apisv1alpha1.APIExportStatus{IdentityHash: "secret-hash"},
+		}
+		if ready {
+			export.Spec.LatestResourceSchemas = []string{"v1.things.example.io"}
+		}
+		return export
+	}
+
+	lister := &fakeProviderLister{
+		providers: []extensionapiv1alpha1.ProviderMetadata{{ObjectMeta: metav1.ObjectMeta{Name: "provider"}}},
+		exports:   []apisv1alpha1.APIExport{export("things", true), export("pending", false)},
+	}
+
+	result, err := marketplaceCatalog(lister, cfg)(context.Background(), &internalversion.ListOptions{})
+	require.NoError(t, err)
+
+	items := result.(*unstructured.UnstructuredList).Items
+	require.Len(t, items, 1)
+
+	var entry v1alpha1.MarketplaceEntry
+	require.NoError(t, runtime.DefaultUnstructuredConverter.FromUnstructured(items[0].Object, &entry))
+	assert.Equal(t, "things-provider", entry.Name)
+	assert.Equal(t, "things", entry.Spec.APIExport.Name)
+	assert.Empty(t, entry.Spec.APIExport.Status.IdentityHash)
+	assert.Empty(t, entry.Spec.APIBindingName)
+	assert.Nil(t, entry.Spec.Installable)
+}
@@ -0,0 +1,199 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+	"github.com/kcp-dev/virtual-workspace-framework/pkg/dynamic/apiserver"
+	registry "github.com/kcp-dev/virtual-workspace-framework/pkg/forwardingregistry"
+	uiv1alpha1 "github.com/platform-mesh/virtual-workspaces/api/ui/v1alpha1"
+
+	"k8s.io/apimachinery/pkg/apis/meta/internalversion"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Reasons a contentconfiguration is excluded, as shown in ContentDiagnostics.
+const (
+	reasonNoConfigurationResult = "no configurationResult"
+	reasonContentForLocal       = "content-for label set, served from its apiexport"
+	reasonEntityMismatch        = "entity mismatch"
+	reasonBindingNotBound       = "binding not bound"
+	reasonConsumerMismatch      = "consumer selector mismatch"
+	reasonInvalidConsumer       = "invalid consumer selector"
+	reasonOverridden            = "overridden by a nearer definition"
+)
+
+// Annotations recording where a contentconfiguration was collected from. They
+// are only set while collecting diagnostics.
+const (
+	diagnosticSourceAnnotation     = "ui.platform-mesh.io/diagnostic-source"
+	diagnosticSourceNameAnnotation = "ui.platform-mesh.io/diagnostic-source-name"
+	diagnosticClusterAnnotation    = "ui.platform-mesh.io/diagnostic-cluster"
+)
+
+type diagnosticsKey struct{}
+
+// diagnostics collects the contentconfigurations excluded while serving a
+// single request. Sources are listed concurrently, so it is safe for concurrent use.
+type diagnostics struct {
+	lock     sync.Mutex
+	excluded []uiv1alpha1.ContentDiagnosticEntry
+}
+
+func withDiagnostics(ctx context.Context) (context.Context, *diagnostics) {
+	d := &diagnostics{}
+	return context.WithValue(ctx, diagnosticsKey{}, d), d
+}
+
+// diagnosticsFrom returns the diagnostics of the request, or nil if the request
+// does not collect diagnostics.
+func diagnosticsFrom(ctx context.Context) *diagnostics {
+	d, _ := ctx.Value(diagnosticsKey{}).(*diagnostics)
+	return d
+}
+
+// exclude records that the named contentconfiguration of source is not served.
+// An empty name excludes the whole source. It is a no-op on nil diagnostics.
+func (d *diagnostics) exclude(source contentSource, name, reason string) {
+	if d == nil {
+		return
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.excluded = append(d.excluded, uiv1alpha1.ContentDiagnosticEntry{
+		Name:       name,
+		Source:     source.kind,
+		SourceName: source.name,
+		Cluster:    source.cluster.String(),
+		Reason:     reason,
+	})
+}
+
+// excludeItem records that item is not served, using the source it was
+// collected from.
+func (d *diagnostics) excludeItem(item unstructured.Unstructured, reason string) {
+	d.exclude(diagnosticSourceOf(item), item.GetName(), reason)
+}
+
+// mismatchReason returns why labels do not match the selector of the source,
+// or an empty string if they do.
+func (s contentSource) mismatchReason(set labels.Set) string {
+	reqs, _ := s.selector.Requirements()
+	for _, req := range reqs {
+		if req.Matches(set) {
+			continue
+		}
+		if reason, ok := s.mismatchReasons[req.Key()]; ok {
+			return reason
+		}
+		return fmt.Sprintf("label selector %s does not match", req.String())
+	}
+	return ""
+}
+
+// collectSourceItems returns the served contentconfigurations of a source. When
+// collecting diagnostics the source is listed with its diagnostic selector, so
+// every item that is dropped here is recorded with the reason.
+func collectSourceItems(ctx context.Context, source contentSource, list *unstructured.UnstructuredList) []unstructured.Unstructured {
+	d := diagnosticsFrom(ctx)
+	if d == nil {
+		return contentConfigurationWithResult(list)
+	}
+
+	var items []unstructured.Unstructured
+	for _, item := range list.Items {
+		if reason := source.mismatchReason(item.GetLabels()); reason != "" {
+			d.exclude(source, item.GetName(), reason)
+			continue
+		}
+
+		_, hasResult, err := unstructured.NestedFieldNoCopy(item.Object, "status", "configurationResult")
+		if err != nil || !hasResult {
+			d.exclude(source, item.GetName(), reasonNoConfigurationResult)
+			continue
+		}
+
+		annotations := item.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[diagnosticSourceAnnotation] = source.kind
+		annotations[diagnosticSourceNameAnnotation] = source.name
+		annotations[diagnosticClusterAnnotation] = source.cluster.String()
+		item.SetAnnotations(annotations)
+
+		items = append(items, item)
+	}
+
+	return items
+}
+
+func diagnosticSourceOf(item unstructured.Unstructured) contentSource {
+	annotations := item.GetAnnotations()
+	return contentSource{
+		kind:    annotations[diagnosticSourceAnnotation],
+		name:    annotations[diagnosticSourceNameAnnotation],
+		cluster: logicalcluster.Name(annotations[diagnosticClusterAnnotation]),
+	}
+}
+
+// CreateDiagnosticsStorageProviderFunc returns a storage provider for the
+// read-only contentdiagnostics resource. Its single object lists every
+// contentconfiguration lister considered on the request and why it was excluded.
+func CreateDiagnosticsStorageProviderFunc(lister registry.ListerFunc) func(ctx context.Context) (apiserver.RestProviderFunc, error) {
+	return createSingletonStorageProviderFunc(uiv1alpha1.ContentDiagnosticName, func(ctx context.Context, options *internalversion.ListOptions) (any, error) {
+		ctx, d := withDiagnostics(ctx)
+
+		result, err := lister(ctx, options)
+		if err != nil {
+			return nil, err
+		}
+
+		return compileDiagnostics(result.(*unstructured.UnstructuredList).Items, d), nil
+	})
+}
+
+// compileDiagnostics lists the included contentconfigurations in the order they
+// are served, followed by the excluded ones sorted by source and name.
+func compileDiagnostics(included []unstructured.Unstructured, d *diagnostics) *uiv1alpha1.ContentDiagnostic {
+	diagnostic := &uiv1alpha1.ContentDiagnostic{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: uiv1alpha1.ContentDiagnosticName,
+		},
+	}
+
+	for _, item := range included {
+		source := diagnosticSourceOf(item)
+		entry := uiv1alpha1.ContentDiagnosticEntry{
+			Name:       item.GetName(),
+			Source:     source.kind,
+			SourceName: source.name,
+			Cluster:    source.cluster.String(),
+			Included:   true,
+		}
+		if item.GetAnnotations()[InactiveAnnotation] == "true" {
+			entry.Reason = item.GetAnnotations()[InactiveReasonAnnotation]
+		}
+		diagnostic.Spec.Entries = append(diagnostic.Spec.Entries, entry)
+	}
+
+	excluded := slices.Clone(d.excluded)
+	slices.SortStableFunc(excluded, func(a, b uiv1alpha1.ContentDiagnosticEntry) int {
+		return cmp.Or(
+			cmp.Compare(a.Source, b.Source),
+			cmp.Compare(a.SourceName, b.SourceName),
+			cmp.Compare(a.Name, b.Name),
+		)
+	})
+	diagnostic.Spec.Entries = append(diagnostic.Spec.Entries, excluded...)
+
+	return diagnostic
+}
@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+	"github.com/kcp-dev/virtual-workspace-framework/pkg/forwardingregistry"
+	uiv1alpha1 "github.com/platform-mesh/virtual-workspaces/api/ui/v1alpha1"
+	"github.com/platform-mesh/virtual-workspaces/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/apis/meta/internalversion"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+)
+
+func TestContentConfigurationLookup_Diagnostics(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.NewServiceConfig()
+	cfg.ConsumerSelectorAnnotation = "ui.platform-mesh.io/consumer-selector"
+	accountPath := logicalcluster.NewPath("root:orgs:my-org:my-account")
+	accountCluster := logicalcluster.Name("my-account")
+
+	exportLabels := func(export, entity string) map[string]string {
+		return map[string]string{cfg.ContentForLabel: export, cfg.EntityLabel: entity}
+	}
+
+	consumerOnly := newCC("provider-gold", map[string]string{cfg.EntityLabel: cfg.AccountEntityName}, true)
+	consumerOnly.SetAnnotations(map[string]string{cfg.ConsumerSelectorAnnotation: "tier=gold"})
+
+	pending := newAPIBinding("pending", "pending.cloud", "pending-ws")
+	_ = unstructured.SetNestedField(pending.Object, "Binding", "status", "phase")
+
+	storage := &forwardingregistry.StoreFuncs{}
+	storage.ListerFunc = multiClusterLister(map[logicalcluster.Name][]unstructured.Unstructured{
+		accountCluster: {
+			newCC("local-home", nil, true),
+			newCC("local-draft", nil, false),
+			newCC("local-projected", exportLabels("openmcp.cloud", cfg.AccountEntityName), true),
+		},
+		"export-ws": {
+			newCC("export-home", exportLabels("openmcp.cloud", cfg.AccountEntityName), true),
+			newCC("export-org", exportLabels("openmcp.cloud", cfg.MainEntityName), true),
+			// content of an export the workspace has not bound is never reported
+			newCC("export-unbound", exportLabels("unbound.cloud", cfg.AccountEntityName), true),
+		},
+		"pending-ws": {
+			newCC("pending-home", exportLabels("pending.cloud", cfg.AccountEntityName), true),
+		},
+		"provider-ws": {
+			newCC("provider-home", map[string]string{cfg.EntityLabel: cfg.AccountEntityName}, true),
+			newCC("provider-org", map[string]string{cfg.EntityLabel: cfg.MainEntityName}, true),
+			consumerOnly,
+		},
+	})
+
+	client := &fakeDynamicClusterClient{
+		objects: map[logicalcluster.Path]map[string][]unstructured.Unstructured{
+			accountPath: {
+				"apibindings": {
+					newAPIBinding("openmcp", "openmcp.cloud", "export-ws"),
+					pending,
+					newAPIBinding("incomplete", "incomplete.cloud", ""),
+				},
+				"logicalclusters": {newLogicalCluster("")},
+			},
+		},
+	}
+
+	wrapper := ContentConfigurationLookup(client, nil, cfg, testProviders(cfg, "provider-ws"), nil)
+	wrapper.Decorate(schema.GroupResource{Group: "ui.platform-mesh.io", Resource: "contentconfigurations"}, storage)
+
+	ctx := WithClusterPath(context.Background(), accountPath)
+	ctx = genericapirequest.WithCluster(ctx, genericapirequest.Cluster{Name: accountCluster})
+
+	// without diagnostics the response is unchanged
+	result, err := storage.List(ctx, &internalversion.ListOptions{})
+	require.NoError(t, err)
+	var gotNames []string
+	for _, item := range result.(*unstructured.UnstructuredList).Items {
+		gotNames = append(gotNames, item.GetName())
+		assert.NotContains(t, item.GetAnnotations(), diagnosticSourceAnnotation)
+	}
+	assert.Equal(t, []string{"local-home", "export-home", "provider-home"}, gotNames)
+
+	diagnosticsCtx, d := withDiagnostics(ctx)
+	result, err = storage.List(diagnosticsCtx, &internalversion.ListOptions{})
+	require.NoError(t, err)
+
+	diagnostic := compileDiagnostics(result.(*unstructured.UnstructuredList).Items, d)
+	assert.Equal(t, []uiv1alpha1.ContentDiagnosticEntry{
+		{Name: "local-home", Source: "local", SourceName: accountPath.String(), Cluster: "my-account", Included: true},
+		{Name: "export-home", Source: "apiexport", SourceName: "openmcp.cloud", Cluster: "export-ws", Included: true},
+		{Name: "provider-home", Source: "provider", SourceName: "provider-ws", Cluster: "provider-ws", Included: true},
+		{Source: "apibinding", SourceName: "incomplete", Reason: skipReasonIncompleteBinding},
+		{Name: "export-org", Source: "apiexport", SourceName: "openmcp.cloud", Cluster: "export-ws", Reason: reasonEntityMismatch},
+		{Name: "pending-home", Source: "apiexport", SourceName: "pending.cloud", Cluster: "pending-ws", Reason: "binding not bound: binding is in phase Binding"},
+		{Name: "local-draft", Source: "local", SourceName: accountPath.String(), Cluster: "my-account", Reason: reasonNoConfigurationResult},
+		{Name: "local-projected", Source: "local", SourceName: accountPath.String(), Cluster: "my-account", Reason: reasonContentForLocal},
+		{Name: "provider-gold", Source: "provider", SourceName: "provider-ws", Cluster: "provider-ws", Reason: reasonConsumerMismatch},
+	}, diagnostic.Spec.Entries)
+	assert.Equal(t, uiv1alpha1.ContentDiagnosticName, diagnostic.Name)
+}
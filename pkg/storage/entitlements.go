@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"context"
+	"maps"
+
+	"github.com/kcp-dev/client-go/dynamic"
+	"github.com/kcp-dev/logicalcluster/v3"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/klog/v2"
+
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+)
+
+// ConsumerLabelsResolver returns the labels of the consuming workspace that
+// contentconfiguration requirements are evaluated against.
+type ConsumerLabelsResolver func(ctx context.Context, path logicalcluster.Path) (labels.Set, error)
+
+// NewConsumerLabelsResolver returns a resolver that merges the labels of the
+// workspace's Workspace object (in its parent) with the labels of its
+// LogicalCluster, the latter taking precedence. A Workspace that cannot be read
+// is ignored, as the LogicalCluster alone is sufficient. The labels are cached
+// per logical cluster for workspaceCacheTTL.
+func NewConsumerLabelsResolver(client dynamic.ClusterInterface) ConsumerLabelsResolver {
+	consumers := cache.NewLRUExpireCache(workspaceCacheSize)
+
+	return func(ctx context.Context, path logicalcluster.Path) (labels.Set, error) {
+		key := path.String()
+		if cluster := genericapirequest.ClusterFrom(ctx); cluster != nil && !cluster.Name.Empty() {
+			key = cluster.Name.String()
+		}
+
+		if cached, ok := consumers.Get(key); ok {
+			return maps.Clone(cached.(labels.Set)), nil
+		}
+
+		consumerLabels := labels.Set{}
+
+		if parent, name := path.Split(); !parent.Empty() {
+			ws, err := client.Cluster(parent).Resource(schema.GroupVersionResource{
+				Group:    "tenancy.kcp.io",
+				Version:  "v1alpha1",
+				Resource: "workspaces",
+			}).Get(ctx, name, metav1.GetOptions{})
+			switch {
+			case err == nil:
+				maps.Copy(consumerLabels, ws.GetLabels())
+			case kerrors.IsNotFound(err) || kerrors.IsForbidden(err):
+				klog.V(8).InfoS("workspace object not readable, using logicalcluster labels only", "path", path, "err", err)
+			default:
+				return nil, err
+			}
+		}
+
+		lc, err := client.Cluster(path).Resource(logicalClusterGVR).Get(ctx, "cluster", metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		maps.Copy(consumerLabels, lc.GetLabels())
+		consumers.Add(key, maps.Clone(consumerLabels), workspaceCacheTTL)
+
+		return consumerLabels, nil
+	}
+}
+
+// filterByConsumerRequirements drops contentconfigurations whose consumer
+// selector annotation does not match the consuming workspace. The workspace
+// labels are only resolved if at least one contentconfiguration declares a
+// requirement. Unparsable selectors never match.
+func filterByConsumerRequirements(
+	ctx context.Context,
+	items []unstructured.Unstructured,
+	annotation string,
+	path logicalcluster.Path,
+	resolveConsumerLabels ConsumerLabelsResolver,
+) ([]unstructured.Unstructured, error) {
+	if annotation == "" {
+		return items, nil
+	}
+
+	var consumerLabels labels.Set
+	results := items[:0]
+	for _, item := range items {
+		requirement, ok := item.GetAnnotations()[annotation]
+		if !ok {
+			results = append(results, item)
+			continue
+		}
+
+		selector, err := labels.Parse(requirement)
+		if err != nil {
+			klog.ErrorS(err, "invalid consumer selector on contentconfiguration", "cc", item.GetName(), "selector", requirement)
+			diagnosticsFrom(ctx).excludeItem(item, reasonInvalidConsumer)
+			continue
+		}
+
+		if consumerLabels == nil {
+			consumerLabels, err = resolveConsumerLabels(ctx, path)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if !selector.Matches(consumerLabels) {
+			klog.V(8).InfoS("contentconfiguration does not match consumer", "cc", item.GetName(), "selector", requirement)
+			diagnosticsFrom(ctx).excludeItem(item, reasonConsumerMismatch)
+			continue
+		}
+
+		results = append(results, item)
+	}
+
+	return results, nil
+}
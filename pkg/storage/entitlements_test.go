@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+)
+
+func TestConsumerLabelsResolver(t *testing.T) {
+	t.Parallel()
+
+	path := logicalcluster.NewPath("root:orgs:acme")
+
+	ws := unstructured.Unstructured{}
+	ws.SetName("acme")
+	ws.SetLabels(map[string]string{"plan": "free", "region": "eu"})
+
+	lc := newLogicalCluster("root:org")
+	lc.SetLabels(map[string]string{"plan": "premium"})
+
+	client := &fakeDynamicClusterClient{
+		objects: map[logicalcluster.Path]map[string][]unstructured.Unstructured{
+			logicalcluster.NewPath("root:orgs"): {"workspaces": {ws}},
+			path:                                {"logicalclusters": {lc}},
+		},
+	}
+
+	consumerLabels, err := NewConsumerLabelsResolver(client)(context.Background(), path)
+	require.NoError(t, err)
+	assert.Equal(t, "premium", consumerLabels["plan"])
+	assert.Equal(t, "eu", consumerLabels["region"])
+}
+
+func TestConsumerLabelsResolver_CachesLabelsPerCluster(t *testing.T) {
+	t.Parallel()
+
+	path := logicalcluster.NewPath("root:orgs:acme")
+
+	lc := newLogicalCluster("root:org")
+	lc.SetLabels(map[string]string{"plan": "premium"})
+
+	client := &fakeDynamicClusterClient{
+		objects: map[logicalcluster.Path]map[string][]unstructured.Unstructured{
+			path: {"logicalclusters": {lc}},
+		},
+	}
+	resolve := NewConsumerLabelsResolver(client)
+	ctx := genericapirequest.WithCluster(context.Background(), genericapirequest.Cluster{Name: "acme-cluster"})
+
+	consumerLabels, err := resolve(ctx, path)
+	require.NoError(t, err)
+	assert.Equal(t, "premium", consumerLabels["plan"])
+	consumerLabels["plan"] = "free"
+
+	// served from the cache, the logicalcluster is not read again
+	delete(client.objects, path)
+	consumerLabels, err = resolve(ctx, path)
+	require.NoError(t, err)
+	assert.Equal(t, "premium", consumerLabels["plan"])
+
+	_, err = resolve(genericapirequest.WithCluster(context.Background(), genericapirequest.Cluster{Name: "other-cluster"}), path)
+	require.Error(t, err)
+}
+
+func TestFilterByConsumerRequirements(t *testing.T) {
+	t.Parallel()
+
+	const annotation = "ui.platform-mesh.io/consumer-selector"
+
+	withRequirement := func(name, selector string) unstructured.Unstructured {
+		cc := newCC(name, nil, true)
+		cc.SetAnnotations(map[string]string{annotation: selector})
+		return cc
+	}
+
+	items := []unstructured.Unstructured{
+		newCC("unrestricted", nil, true),
+		withRequirement("premium-only", "plan=premium"),
+		withRequirement("free-only", "plan=free"),
+		withRequirement("beta", "features.platform-mesh.io/beta"),
+		withRequirement("broken", "plan in ("),
+	}
+
+	calls := 0
+	resolve := func(_ context.Context, _ logicalcluster.Path) (labels.Set, error) {
+		calls++
+		return labels.Set{"plan": "premium"}, nil
+	}
+
+	result, err := filterByConsumerRequirements(context.Background(), items, annotation, logicalcluster.NewPath("root:orgs:acme"), resolve)
+	require.NoError(t, err)
+
+	var gotNames []string
+	for _, item := range result {
+		gotNames = append(gotNames, item.GetName())
+	}
+	assert.Equal(t, []string{"unrestricted", "premium-only"}, gotNames)
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kcp-dev/client-go/dynamic"
+	"github.com/kcp-dev/logicalcluster/v3"
+	kcptenancyv1alpha1 "github.com/kcp-dev/sdk/apis/tenancy/v1alpha1"
+	"github.com/platform-mesh/virtual-workspaces/pkg/config"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/klog/v2"
+
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+)
+
+const (
+	// workspaceCacheSize bounds the number of workspaces whose type or logical
+	// cluster is kept, workspaceCacheTTL how long a path is trusted to still
+	// point to the same logical cluster.
+	workspaceCacheSize = 8192
+	workspaceCacheTTL  = 10 * time.Minute
+)
+
+var logicalClusterGVR = schema.GroupVersionResource{
+	Group:    "core.kcp.io",
+	Version:  "v1alpha1",
+	Resource: "logicalclusters",
+}
+
+// EntityTypeResolver determines the entity type (e.g. "main" or an account
+// entity) a workspace path represents. The entity type selects which
+// contentconfigurations from export and provider workspaces apply.
+type EntityTypeResolver func(ctx context.Context, path logicalcluster.Path) (string, error)
+
+// NewEntityTypeResolver returns a resolver that looks up the WorkspaceType of
+// the given workspace on its LogicalCluster and maps it via
+// cfg.EntityTypeMappings. The type of a logical cluster never changes, so it is
+// cached per logical cluster. Workspaces whose type is not mapped are rejected.
+// Without mappings the entity type is derived from the path: workspaces
+// directly below an "orgs" workspace are the main entity, everything else is
+// treated as an account.
+func NewEntityTypeResolver(client dynamic.ClusterInterface, cfg config.ServiceConfig) EntityTypeResolver {
+	workspaceTypes := cache.NewLRUExpireCache(workspaceCacheSize)
+
+	return func(ctx context.Context, path logicalcluster.Path) (string, error) {
+		if len(cfg.EntityTypeMappings) > 0 {
+			key := path.String()
+			if cluster := genericapirequest.ClusterFrom(ctx); cluster != nil && !cluster.Name.Empty() {
+				key = cluster.Name.String()
+			}
+
+			workspaceType, ok := workspaceTypes.Get(key)
+			if !ok {
+				lc, err := client.Cluster(path).Resource(logicalClusterGVR).Get(ctx, "cluster", metav1.GetOptions{})
+				if err != nil {
+					return "", err
+				}
+
+				workspaceType = lc.GetAnnotations()[kcptenancyv1alpha1.LogicalClusterTypeAnnotationKey]
+				workspaceTypes.Add(key, workspaceType, workspaceCacheTTL)
+			}
+
+			entityType, ok := cfg.EntityTypeMappings[workspaceType.(string)]
+			if !ok {
+				err := kerrors.NewBadRequest(fmt.Sprintf("no entity type is mapped for workspace type %q", workspaceType))
+				klog.ErrorS(err, "failed to resolve entity type", "path", path)
+				return "", err
+			}
+			return entityType, nil
+		}
+
+		parentPath, ok := path.Parent()
+		if !ok {
+			klog.ErrorS(kerrors.NewBadRequest("parent cluster path not found"), "path", path)
+			return "", kerrors.NewBadRequest("parent cluster path not found")
+		}
+
+		if strings.HasSuffix(parentPath.String(), "orgs") {
+			return cfg.MainEntityName, nil
+		}
+
+		return cfg.AccountEntityName, nil
+	}
+}
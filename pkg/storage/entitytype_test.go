@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+	kcptenancyv1alpha1 "github.com/kcp-dev/sdk/apis/tenancy/v1alpha1"
+	"github.com/platform-mesh/virtual-workspaces/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+)
+
+func newLogicalCluster(workspaceType string) unstructured.Unstructured {
+	lc := unstructured.Unstructured{}
+	lc.SetAPIVersion("core.kcp.io/v1alpha1")
+	lc.SetKind("LogicalCluster")
+	lc.SetName("cluster")
+	if workspaceType != "" {
+		lc.SetAnnotations(map[string]string{
+			kcptenancyv1alpha1.LogicalClusterTypeAnnotationKey: workspaceType,
+		})
+	}
+	return lc
+}
+
+func TestEntityTypeResolver(t *testing.T) {
+	t.Parallel()
+
+	teamPath := logicalcluster.NewPath("root:orgs:acme:team-a")
+
+	tests := []struct {
+		name          string
+		mappings      map[string]string
+		path          logicalcluster.Path
+		workspaceType string
+		expected      string
+		expectErr     bool
+	}{
+		{
+			name:     "org workspace without mappings is the main entity",
+			path:     logicalcluster.NewPath("root:orgs:acme"),
+			expected: "main",
+		},
+		{
+			name:     "nested workspace without mappings is an account",
+			path:     teamPath,
+			expected: "core_platform-mesh_io_account",
+		},
+		{
+			name:          "mapped workspace type wins over the path",
+			mappings:      map[string]string{"root:team": "team"},
+			path:          teamPath,
+			workspaceType: "root:team",
+			expected:      "team",
+		},
+		{
+			name:          "unmapped workspace type is an error",
+			mappings:      map[string]string{"root:project": "project"},
+			path:          teamPath,
+			workspaceType: "root:team",
+			expectErr:     true,
+		},
+		{
+			name:      "missing logicalcluster is an error when mappings are configured",
+			mappings:  map[string]string{"root:team": "team"},
+			path:      logicalcluster.NewPath("root:orgs:acme:unknown"),
+			expectErr: true,
+		},
+		{
+			name:      "workspace without parent is an error",
+			path:      logicalcluster.NewPath("root"),
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := config.NewServiceConfig()
+			cfg.EntityTypeMappings = tt.mappings
+
+			client := &fakeDynamicClusterClient{
+				objects: map[logicalcluster.Path]map[string][]unstructured.Unstructured{
+					teamPath: {"logicalclusters": {newLogicalCluster(tt.workspaceType)}},
+				},
+			}
+
+			entityType, err := NewEntityTypeResolver(client, cfg)(context.Background(), tt.path)
+			if tt.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, entityType)
+		})
+	}
+}
+
+func TestEntityTypeResolver_CachesWorkspaceTypePerCluster(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.NewServiceConfig()
+	cfg.EntityTypeMappings = map[string]string{"root:team": "team"}
+
+	teamPath := logicalcluster.NewPath("root:orgs:acme:team-a")
+	client := &fakeDynamicClusterClient{
+		objects: map[logicalcluster.Path]map[string][]unstructured.Unstructured{
+			teamPath: {"logicalclusters": {newLogicalCluster("root:team")}},
+		},
+	}
+	resolve := NewEntityTypeResolver(client, cfg)
+	ctx := genericapirequest.WithCluster(context.Background(), genericapirequest.Cluster{Name: "team-a-cluster"})
+
+	entityType, err := resolve(ctx, teamPath)
+	require.NoError(t, err)
+	assert.Equal(t, "team", entityType)
+
+	// served from the cache, the logicalcluster is not read again
+	delete(client.objects, teamPath)
+	entityType, err = resolve(ctx, teamPath)
+	require.NoError(t, err)
+	assert.Equal(t, "team", entityType)
+
+	_, err = resolve(genericapirequest.WithCluster(context.Background(), genericapirequest.Cluster{Name: "other-cluster"}), teamPath)
+	require.Error(t, err)
+}
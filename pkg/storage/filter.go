@@ -4,11 +4,12 @@ import (
 	"context"
 	"fmt"
 	"slices"
-	"strings"
 
 	"github.com/kcp-dev/client-go/dynamic"
+	"github.com/kcp-dev/kcp/pkg/authorization/delegated"
 	"github.com/kcp-dev/logicalcluster/v3"
 	"github.com/kcp-dev/multicluster-provider/apiexport"
+	mcpcache "github.com/kcp-dev/multicluster-provider/pkg/cache"
 	apisv1alpha1 "github.com/kcp-dev/sdk/apis/apis/v1alpha1"
 	"github.com/kcp-dev/virtual-workspace-framework/pkg/forwardingregistry"
 	extensionapiv1alpha1 "github.com/platform-mesh/extension-manager-operator/api/v1alpha1"
@@ -61,101 +62,178 @@ func contentConfigurationWithResult(cc *unstructured.UnstructuredList) []unstruc
 	return results
 }
 
-func ContentConfigurationLookup(client dynamic.ClusterInterface, cfg config.ServiceConfig, providerWorkspaceID string) forwardingregistry.StorageWrapper {
+// ProviderWorkspace is a resolved provider workspace whose contentconfigurations
+// are served to all workspaces, selected by entity type in EntityLabel.
+type ProviderWorkspace struct {
+	Path        logicalcluster.Path
+	Cluster     logicalcluster.Name
+	EntityLabel string
+}
+
+// ContentConfigurationLookup merges the contentconfigurations of the requested
+// workspace with those of its bound export workspaces and the provider workspaces.
+// If cache is set, export and provider workspaces are served from it; only the
+// requested workspace is always listed live. If userClient is set, the requested
+// workspace and its APIBindings are read with it instead of client, e.g. as the
+// caller with ImpersonatingClusterClient.
+func ContentConfigurationLookup(client dynamic.ClusterInterface, userClient forwardingregistry.DynamicClusterClientFunc, cfg config.ServiceConfig, providers []ProviderWorkspace, cache ContentConfigurationCache) forwardingregistry.StorageWrapper {
+	lookup := newContentConfigurationLookup(client, userClient, cfg, providers, cache)
 
 	return forwardingregistry.StorageWrapperFunc(func(resource schema.GroupResource, storage *forwardingregistry.StoreFuncs) {
-		delegateLister := storage.ListerFunc
-		storage.ListerFunc = func(ctx context.Context, options *internalversion.ListOptions) (runtime.Object, error) {
+		storage.ListerFunc = lookup(storage.ListerFunc)
+	})
+}
+
+// ContentConfigurationLister returns a lister for the same merged
+// contentconfigurations as ContentConfigurationLookup, for consumers outside of
+// the contentconfigurations storage.
+func ContentConfigurationLister(client dynamic.ClusterInterface, userClient forwardingregistry.DynamicClusterClientFunc, cfg config.ServiceConfig, providers []ProviderWorkspace, cache ContentConfigurationCache) forwardingregistry.ListerFunc {
+	return newContentConfigurationLookup(client, userClient, cfg, providers, cache)(dynamicLister(client, ContentConfigurationGVR))
+}
+
+func newContentConfigurationLookup(client dynamic.ClusterInterface, userClient forwardingregistry.DynamicClusterClientFunc, cfg config.ServiceConfig, providers []ProviderWorkspace, cache ContentConfigurationCache) func(forwardingregistry.ListerFunc) forwardingregistry.ListerFunc {
+	resolveEntityType := NewEntityTypeResolver(client, cfg)
+	resolveConsumerLabels := NewConsumerLabelsResolver(client)
+	resolveClusterName := NewClusterNameResolver(client)
+
+	return func(delegateLister forwardingregistry.ListerFunc) forwardingregistry.ListerFunc {
+		sourceLister := cachedLister(cache, delegateLister)
+		return func(ctx context.Context, options *internalversion.ListOptions) (runtime.Object, error) {
+
+			// The caller's label requirements are combined with the fixed
+			// requirements of every source below, so filtering by custom labels
+			// behaves the same for local, export and provider contentconfigurations.
+			var callerReqs labels.Requirements
+			if options.LabelSelector != nil {
+				reqs, selectable := options.LabelSelector.Requirements()
+				if !selectable {
+					return &unstructured.UnstructuredList{}, nil
+				}
+				callerReqs = reqs
+			}
 
 			// Exclude CCs with content-for label from the current workspace.
 			// These are provider-published CCs projected via APIBindings and will be
 			// fetched from their source export workspaces below with proper filtering.
-			localOpts := options.DeepCopy()
+			path, ok := ClusterPathFrom(ctx)
+			if !ok {
+				klog.Error("cluster path not found in context")
+				return nil, kerrors.NewBadRequest("cluster path not found in context")
+			}
+
 			noContentFor, err := labels.Parse("!" + cfg.ContentForLabel)
 			if err != nil {
 				return nil, err
 			}
-			if localOpts.LabelSelector != nil {
-				reqs, selectable := localOpts.LabelSelector.Requirements()
-				if !selectable {
-					return &unstructured.UnstructuredList{}, nil
+			local := contentSource{
+				kind:            "local",
+				name:            path.String(),
+				selector:        noContentFor.Add(callerReqs...),
+				mismatchReasons: map[string]string{cfg.ContentForLabel: reasonContentForLocal},
+			}
+			if cluster := genericapirequest.ClusterFrom(ctx); cluster != nil {
+				local.cluster = cluster.Name
+			}
+
+			localOpts := options.DeepCopy()
+			localOpts.LabelSelector = local.selector
+			if diagnosticsFrom(ctx) != nil {
+				localOpts.LabelSelector = labels.Everything()
+			}
+
+			// Export and provider workspaces are always read with the service
+			// identity below, the caller usually has no access to them.
+			localLister, workspaceClient := delegateLister, client
+			if userClient != nil {
+				workspaceClient, err = userClient(ctx)
+				if err != nil {
+					return nil, err
 				}
-				noContentFor = noContentFor.Add(reqs...)
+				localLister = dynamicLister(workspaceClient, ContentConfigurationGVR)
 			}
-			localOpts.LabelSelector = noContentFor
 
-			result, err := delegateLister.List(ctx, localOpts)
+			result, err := localLister.List(ctx, localOpts)
 			if err != nil {
 				return nil, err
 			}
 
 			ul, _ := result.(*unstructured.UnstructuredList)
-			ul.Items = contentConfigurationWithResult(ul)
+			ul.Items = collectSourceItems(ctx, local, ul)
 
-			path, ok := ClusterPathFrom(ctx)
-			if !ok {
-				klog.Error("cluster path not found in context")
-				return nil, kerrors.NewBadRequest("cluster path not found in context")
+			apiBindings, err := workspaceClient.Cluster(path).Resource(apiBindingGVR).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return nil, err
 			}
 
-			apiBindings, err := client.Cluster(path).Resource(schema.GroupVersionResource{
-				Group:    "apis.kcp.io",
-				Version:  "v1alpha1",
-				Resource: "apibindings",
-			}).List(ctx, metav1.ListOptions{})
+			entityType, err := resolveEntityType(ctx, path)
 			if err != nil {
 				return nil, err
 			}
 
-			parentPath, ok := path.Parent()
-			if !ok {
-				klog.ErrorS(kerrors.NewBadRequest("parent cluster path not found"), "path", path)
-				return nil, kerrors.NewBadRequest("parent cluster path not found")
-			}
+			klog.V(8).InfoS("using entity type", "entityType", entityType)
 
-			entityType := cfg.AccountEntityName
-			if strings.HasSuffix(parentPath.String(), "orgs") {
-				entityType = cfg.MainEntityName
+			// Inherited contentconfigurations are listed along with the other
+			// sources, but merged with the local ones by name below.
+			var sources []contentSource
+			if cfg.InheritContentConfigurations {
+				sources, err = ancestorSources(ctx, cfg, path, entityType, callerReqs, resolveClusterName)
+				if err != nil {
+					return nil, err
+				}
 			}
-
-			klog.V(8).InfoS("using entity type", "entityType", entityType)
+			inherited := len(sources)
 
 			err = apiBindings.EachListItem(func(o runtime.Object) error {
 				binding := o.(*unstructured.Unstructured)
 
-				apiExportName, ok, err := unstructured.NestedString(binding.Object, "spec", "reference", "export", "name")
-				if err != nil || !ok {
-					klog.ErrorS(err, "failed to get apiExportName from apibinding", "binding", binding.GetName())
-					return err
-				}
-
-				apiExportWorkspacePath, ok, err := unstructured.NestedString(binding.Object, "status", "apiExportClusterName")
-				if err != nil || !ok {
-					klog.ErrorS(err, "failed to get apiExportWorkspacePath from apibinding", "binding", binding.GetName())
-					return err
+				// Content of bindings that are not bound would link to APIs that do
+				// not work (yet), so it is only included in diagnostic mode.
+				// When collecting diagnostics their content is still listed, but
+				// only to report it as excluded.
+				var excludeReason string
+				inactiveReason := apiBindingInactiveReason(binding)
+				if inactiveReason != "" && !cfg.IncludeInactiveBindings {
+					klog.V(4).InfoS("skipping inactive apibinding", "binding", binding.GetName(), "reason", inactiveReason)
+					skippedContentSources.WithLabelValues("apibinding", skipReasonInactiveBinding).Inc()
+					if diagnosticsFrom(ctx) == nil {
+						return nil
+					}
+					excludeReason = fmt.Sprintf("%s: %s", reasonBindingNotBound, inactiveReason)
+					inactiveReason = ""
 				}
 
-				exportCtx := genericapirequest.WithCluster(ctx, genericapirequest.Cluster{
-					Name: logicalcluster.Name(apiExportWorkspacePath),
-				})
-
-				exportOpts := options.DeepCopy()
-				exportOpts.LabelSelector = labels.SelectorFromValidatedSet(map[string]string{
-					cfg.ContentForLabel: apiExportName,
-					cfg.EntityLabel:     entityType,
-				})
-
-				apiExportCCs, err := delegateLister.List(exportCtx, exportOpts)
-				if kerrors.IsNotFound(err) {
+				// Bindings that are still binding (or otherwise incomplete) are skipped,
+				// so they do not take the content of all other bindings down with them.
+				apiExportName, ok, err := unstructured.NestedString(binding.Object, "spec", "reference", "export", "name")
+				if err != nil || !ok || apiExportName == "" {
+					skipContentSource(ctx, "apibinding", binding.GetName(), skipReasonIncompleteBinding, err)
 					return nil
 				}
 
-				if err != nil {
-					klog.ErrorS(err, "failed to list contentconfigurations from apiexport", "export", apiExportName, "workspace", apiExportWorkspacePath)
-					return err
+				apiExportWorkspacePath, ok, err := unstructured.NestedString(binding.Object, "status", "apiExportClusterName")
+				if err != nil || !ok || apiExportWorkspacePath == "" {
+					skipContentSource(ctx, "apibinding", binding.GetName(), skipReasonIncompleteBinding, err)
+					return nil
 				}
 
-				ul.Items = append(ul.Items, contentConfigurationWithResult(apiExportCCs.(*unstructured.UnstructuredList))...)
+				sources = append(sources, contentSource{
+					kind:    "apiexport",
+					name:    apiExportName,
+					cluster: logicalcluster.Name(apiExportWorkspacePath),
+					selector: labels.SelectorFromValidatedSet(map[string]string{
+						cfg.ContentForLabel: apiExportName,
+						cfg.EntityLabel:     entityType,
+					}).Add(callerReqs...),
+					// content published for other exports of the same workspace
+					// is none of the caller's business, not even in diagnostics
+					diagnosticSelector: labels.SelectorFromValidatedSet(map[string]string{
+						cfg.ContentForLabel: apiExportName,
+					}),
+					optional:        true,
+					inactiveReason:  inactiveReason,
+					excludeReason:   excludeReason,
+					mismatchReasons: map[string]string{cfg.EntityLabel: reasonEntityMismatch},
+				})
 
 				return nil
 			})
@@ -163,104 +241,201 @@ func ContentConfigurationLookup(client dynamic.ClusterInterface, cfg config.Serv
 				return nil, err
 			}
 
-			providerCtx := genericapirequest.WithCluster(ctx, genericapirequest.Cluster{
-				Name: logicalcluster.Name(providerWorkspaceID),
-			})
+			for _, provider := range providers {
+				sources = append(sources, contentSource{
+					kind:    "provider",
+					name:    provider.Path.String(),
+					cluster: provider.Cluster,
+					selector: labels.SelectorFromValidatedSet(map[string]string{
+						provider.EntityLabel: entityType,
+					}).Add(callerReqs...),
+				})
+			}
 
-			providerOpts := options.DeepCopy()
-			providerOpts.LabelSelector = labels.SelectorFromValidatedSet(map[string]string{
-				cfg.EntityLabel: entityType,
-			})
+			if err := limitFanOut(ctx, len(sources)); err != nil {
+				return nil, err
+			}
 
-			providerCCs, err := delegateLister.List(providerCtx, providerOpts)
+			sourceItems, err := listContentSources(ctx, sourceLister, options, sources, cfg.ContentSourceConcurrency, cfg.ContentSourceTimeout)
+			auditSources(ctx, append([]contentSource{local}, sources...))
 			if err != nil {
-				klog.ErrorS(err, "failed to list contentconfigurations from provider workspace", "workspace", providerWorkspaceID)
 				return nil, err
 			}
 
-			ul.Items = append(ul.Items, contentConfigurationWithResult(providerCCs.(*unstructured.UnstructuredList))...)
+			ul.Items = mergeInherited(ctx, ul.Items, sources[:inherited], sourceItems[:inherited])
+			for _, items := range sourceItems[inherited:] {
+				ul.Items = append(ul.Items, items...)
+			}
+
+			ul.Items, err = filterByConsumerRequirements(ctx, ul.Items, cfg.ConsumerSelectorAnnotation, path, resolveConsumerLabels)
+			if err != nil {
+				return nil, err
+			}
 
 			return ul, nil
 		}
-	})
+	}
 }
 
-func Marketplace(provider *apiexport.Provider, cfg config.ServiceConfig) forwardingregistry.StorageWrapper {
+// Marketplace lists an entry per APIExport of every provider, marked with the
+// APIBinding installing it in the requested workspace and whether the caller
+// may install it. With cfg.HideUninstallableMarketplaceEntries, entries the
+// caller may not install are left out unless they are installed already.
+// If userClient is set, the APIBindings of the requested workspace are read
+// with it instead of the provider client, e.g. as the caller with
+// ImpersonatingClusterClient.
+func Marketplace(provider *apiexport.Provider, userClient forwardingregistry.DynamicClusterClientFunc, cfg config.ServiceConfig, authz delegated.Cache) forwardingregistry.StorageWrapper {
 	return forwardingregistry.StorageWrapperFunc(func(resource schema.GroupResource, storage *forwardingregistry.StoreFuncs) {
 		storage.ListerFunc = func(ctx context.Context, options *internalversion.ListOptions) (runtime.Object, error) {
 			cluster := genericapirequest.ClusterFrom(ctx)
-
-			cl, err := provider.Get(ctx, multicluster.ClusterName(cluster.Name.String()))
+			installable, err := newInstallChecker(ctx, authz, cluster.Name)
 			if err != nil {
-				return nil, fmt.Errorf("failed to get cluster from provider: %w", err)
+				return nil, err
 			}
 
 			// Get APIBindings for this specific cluster
-			installedAPIBindings := &apisv1alpha1.APIBindingList{}
-			if err := cl.GetClient().List(ctx, installedAPIBindings); err != nil {
-				return nil, fmt.Errorf("failed to list apibindings: %w", err)
+			installedAPIBindings, err := listAPIBindings(ctx, provider, userClient, cluster.Name)
+			if err != nil {
+				return nil, err
 			}
 
-			lister := provider.Lister()
-
-			var providerList extensionapiv1alpha1.ProviderMetadataList
-			if err := lister.List(ctx, &providerList); err != nil {
-				return nil, fmt.Errorf("failed to list providermetadatas: %w", err)
+			// APIExports and ProviderMetadata are served from the provider cache,
+			// which spans all provider workspaces.
+			local := contentSource{kind: "local", cluster: cluster.Name}
+			if path, ok := ClusterPathFrom(ctx); ok {
+				local.name = path.String()
 			}
+			auditSources(ctx, []contentSource{local, {kind: "apiexportendpointslice", name: cfg.ResourceAPIExportEndpointSliceName}})
 
-			var results unstructured.UnstructuredList
-			results.SetGroupVersionKind(v1alpha1.GroupVersion.WithKind("MarketplaceEntryList"))
-
-			// For each provider, find matching APIExports across all shards
-			for _, provider := range providerList.Items {
-				exportList := &apisv1alpha1.APIExportList{}
+			return marketplaceEntries(ctx, provider.Lister(), cfg, func(export *apisv1alpha1.APIExport, spec *v1alpha1.MarketplaceEntrySpec) bool {
+				idx := slices.IndexFunc(installedAPIBindings.Items, func(item apisv1alpha1.APIBinding) bool {
+					return item.Spec.Reference.Export.Name == export.Name &&
+						item.Status.APIExportClusterName == export.Annotations["kcp.io/cluster"]
+				})
+				if idx != -1 {
+					spec.APIBindingName = installedAPIBindings.Items[idx].Name
+				}
 
-				if err := lister.List(ctx, exportList, &client.ListOptions{
-					LabelSelector: labels.SelectorFromValidatedSet(map[string]string{
-						cfg.ContentForLabel: provider.GetName(),
-					}),
-				}); err != nil {
-					return nil, fmt.Errorf("failed to list apiexports for provider %s: %w", provider.GetName(), err)
+				canInstall := installable.canInstall(ctx, export)
+				if !canInstall && spec.APIBindingName == "" && cfg.HideUninstallableMarketplaceEntries {
+					return false
 				}
+				spec.Installable = &canInstall
 
-				for _, export := range exportList.Items {
-					if len(export.Spec.LatestResourceSchemas) == 0 {
-						continue
-					}
+				return true
+			})
+		}
+	})
+}
 
-					idx := slices.IndexFunc(installedAPIBindings.Items, func(item apisv1alpha1.APIBinding) bool {
-						return item.Spec.Reference.Export.Name == export.Name &&
-							item.Status.APIExportClusterName == export.Annotations["kcp.io/cluster"]
-					})
+// MarketplaceCatalog lists the same entries as Marketplace, but without any
+// state of a workspace or user, for anonymous browsing. It reads the provider
+// cache only and never contacts kcp on behalf of the caller.
+func MarketplaceCatalog(provider *apiexport.Provider, cfg config.ServiceConfig) forwardingregistry.ListerFunc {
+	return marketplaceCatalog(provider.Lister(), cfg)
+}
 
-					var apiBindingName string
-					if idx != -1 {
-						apiBindingName = installedAPIBindings.Items[idx].Name
-					}
+func marketplaceCatalog(lister mcpcache.Lister, cfg config.ServiceConfig) forwardingregistry.ListerFunc {
+	return func(ctx context.Context, options *internalversion.ListOptions) (runtime.Object, error) {
+		auditSources(ctx, []contentSource{{kind: "apiexportendpointslice", name: cfg.ResourceAPIExportEndpointSliceName}})
 
-					provider.ManagedFields = nil // clear managed fields to declutter the output
-					export.ManagedFields = nil
-
-					unstructuredEntry, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&v1alpha1.MarketplaceEntry{
-						ObjectMeta: metav1.ObjectMeta{
-							Name: fmt.Sprintf("%s-%s", export.Name, provider.Name), // TODO: we might need to fix the name length to not exceed the kubernetes limit
-						},
-						Spec: v1alpha1.MarketplaceEntrySpec{
-							ProviderMetadata: *provider.DeepCopy(),
-							APIExport:        *export.DeepCopy(),
-							APIBindingName:   apiBindingName,
-						},
-					})
-					if err != nil {
-						return nil, fmt.Errorf("failed to convert marketplace entry to unstructured for export %s and provider %s: %w", export.Name, provider.Name, err)
-					}
+		return marketplaceEntries(ctx, lister, cfg, func(export *apisv1alpha1.APIExport, spec *v1alpha1.MarketplaceEntrySpec) bool {
+			// the status holds the identity hash and virtual workspace URLs of
+			// the export, which only consumers binding it need
+			spec.APIExport.Status = apisv1alpha1.APIExportStatus{}
+			return true
+		})
+	}
+}
 
-					us := unstructured.Unstructured{Object: unstructuredEntry}
-					us.SetGroupVersionKind(v1alpha1.GroupVersion.WithKind("MarketplaceEntry"))
-					results.Items = append(results.Items, us)
-				}
+// marketplaceEntries builds an entry per APIExport of every provider in
+// lister. decorate adds the state of the request to the spec of an entry, or
+// returns false to leave the entry out.
+func marketplaceEntries(ctx context.Context, lister mcpcache.Lister, cfg config.ServiceConfig, decorate func(export *apisv1alpha1.APIExport, spec *v1alpha1.MarketplaceEntrySpec) bool) (*unstructured.UnstructuredList, error) {
+	var providerList extensionapiv1alpha1.ProviderMetadataList
+	if err := lister.List(ctx, &providerList); err != nil {
+		return nil, fmt.Errorf("failed to list providermetadatas: %w", err)
+	}
+
+	var results unstructured.UnstructuredList
+	results.SetGroupVersionKind(v1alpha1.GroupVersion.WithKind("MarketplaceEntryList"))
+
+	// For each provider, find matching APIExports across all shards
+	for _, provider := range providerList.Items {
+		exportList := &apisv1alpha1.APIExportList{}
+
+		if err := lister.List(ctx, exportList, &client.ListOptions{
+			LabelSelector: labels.SelectorFromValidatedSet(map[string]string{
+				cfg.ContentForLabel: provider.GetName(),
+			}),
+		}); err != nil {
+			return nil, fmt.Errorf("failed to list apiexports for provider %s: %w", provider.GetName(), err)
+		}
+
+		for _, export := range exportList.Items {
+			if len(export.Spec.LatestResourceSchemas) == 0 {
+				continue
+			}
+
+			provider.ManagedFields = nil // clear managed fields to declutter the output
+			export.ManagedFields = nil
+
+			spec := v1alpha1.MarketplaceEntrySpec{
+				ProviderMetadata: *provider.DeepCopy(),
+				APIExport:        *export.DeepCopy(),
 			}
-			return &results, nil
+			if !decorate(&export, &spec) {
+				continue
+			}
+
+			unstructuredEntry, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&v1alpha1.MarketplaceEntry{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: fmt.Sprintf("%s-%s", export.Name, provider.Name), // TODO: we might need to fix the name length to not exceed the kubernetes limit
+				},
+				Spec: spec,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert marketplace entry to unstructured for export %s and provider %s: %w", export.Name, provider.Name, err)
+			}
+
+			us := unstructured.Unstructured{Object: unstructuredEntry}
+			us.SetGroupVersionKind(v1alpha1.GroupVersion.WithKind("MarketplaceEntry"))
+			results.Items = append(results.Items, us)
 		}
-	})
+	}
+
+	return &results, nil
+}
+
+// listAPIBindings lists the APIBindings of cluster with userClient if set, or
+// else with the provider client.
+func listAPIBindings(ctx context.Context, provider *apiexport.Provider, userClient forwardingregistry.DynamicClusterClientFunc, cluster logicalcluster.Name) (*apisv1alpha1.APIBindingList, error) {
+	installedAPIBindings := &apisv1alpha1.APIBindingList{}
+
+	if userClient == nil {
+		cl, err := provider.Get(ctx, multicluster.ClusterName(cluster.String()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get cluster from provider: %w", err)
+		}
+
+		if err := cl.GetClient().List(ctx, installedAPIBindings); err != nil {
+			return nil, fmt.Errorf("failed to list apibindings: %w", err)
+		}
+		return installedAPIBindings, nil
+	}
+
+	client, err := userClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := client.Cluster(cluster.Path()).Resource(apisv1alpha1.SchemeGroupVersion.WithResource("apibindings")).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list apibindings: %w", err)
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(list.UnstructuredContent(), installedAPIBindings); err != nil {
+		return nil, fmt.Errorf("failed to convert apibindings: %w", err)
+	}
+
+	return installedAPIBindings, nil
 }
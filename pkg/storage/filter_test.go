@@ -10,6 +10,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/internalversion"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -22,6 +23,7 @@ import (
 	"github.com/kcp-dev/virtual-workspace-framework/pkg/forwardingregistry"
 
 	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/apiserver/pkg/warning"
 )
 
 func newCC(name string, ccLabels map[string]string, hasResult bool) unstructured.Unstructured {
@@ -54,29 +56,46 @@ func toInterfaceMap(m map[string]string) map[string]interface{} {
 }
 
 // fakeDynamicClusterClient implements kcpdynamic.ClusterInterface for testing.
-// It returns an empty APIBinding list so the export-workspace loop is a no-op.
+// It serves the objects configured per workspace path and resource, and empty
+// lists for everything else, so the export-workspace loop is a no-op by default.
 type fakeDynamicClusterClient struct {
 	kcpdynamic.ClusterInterface
+
+	objects map[logicalcluster.Path]map[string][]unstructured.Unstructured
 }
 
-func (f *fakeDynamicClusterClient) Cluster(_ logicalcluster.Path) dynamic.Interface {
-	return &fakeDynamicInterface{}
+func (f *fakeDynamicClusterClient) Cluster(path logicalcluster.Path) dynamic.Interface {
+	return &fakeDynamicInterface{objects: f.objects[path]}
 }
 
 type fakeDynamicInterface struct {
 	dynamic.Interface
+
+	objects map[string][]unstructured.Unstructured
 }
 
-func (f *fakeDynamicInterface) Resource(_ schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
-	return &fakeNamespaceableResource{}
+func (f *fakeDynamicInterface) Resource(gvr schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	return &fakeNamespaceableResource{resource: gvr.GroupResource(), items: f.objects[gvr.Resource]}
 }
 
 type fakeNamespaceableResource struct {
 	dynamic.NamespaceableResourceInterface
+
+	resource schema.GroupResource
+	items    []unstructured.Unstructured
+}
+
+func (f *fakeNamespaceableResource) Get(_ context.Context, name string, _ metav1.GetOptions, _ ...string) (*unstructured.Unstructured, error) {
+	for _, item := range f.items {
+		if item.GetName() == name {
+			return item.DeepCopy(), nil
+		}
+	}
+	return nil, kerrors.NewNotFound(f.resource, name)
 }
 
 func (f *fakeNamespaceableResource) List(_ context.Context, _ metav1.ListOptions) (*unstructured.UnstructuredList, error) {
-	return &unstructured.UnstructuredList{}, nil
+	return &unstructured.UnstructuredList{Items: f.items}, nil
 }
 
 func (f *fakeNamespaceableResource) Watch(_ context.Context, _ metav1.ListOptions) (watch.Interface, error) {
@@ -88,13 +107,20 @@ func (f *fakeNamespaceableResource) Watch(_ context.Context, _ metav1.ListOption
 // returns empty lists for other cluster contexts (export workspaces, provider workspace).
 // It applies label selector filtering to simulate kcp apiserver behavior.
 func clusterAwareLister(allCCs []unstructured.Unstructured, accountCluster logicalcluster.Name) forwardingregistry.ListerFunc {
+	return multiClusterLister(map[logicalcluster.Name][]unstructured.Unstructured{
+		accountCluster: allCCs,
+	})
+}
+
+// multiClusterLister creates a mock ListerFunc that returns the CCs configured for
+// the cluster in the request context, filtered by the requested label selector.
+func multiClusterLister(ccsByCluster map[logicalcluster.Name][]unstructured.Unstructured) forwardingregistry.ListerFunc {
 	return func(ctx context.Context, opts *internalversion.ListOptions) (runtime.Object, error) {
 		cluster := genericapirequest.ClusterFrom(ctx)
 
-		// Only return CCs for the account workspace; other clusters return empty.
 		var candidates []unstructured.Unstructured
-		if cluster != nil && cluster.Name == accountCluster {
-			candidates = allCCs
+		if cluster != nil {
+			candidates = ccsByCluster[cluster.Name]
 		}
 
 		result := &unstructured.UnstructuredList{}
@@ -111,6 +137,44 @@ func clusterAwareLister(allCCs []unstructured.Unstructured, accountCluster logic
 	}
 }
 
+// testProviders returns provider workspaces for the given clusters using the
+// default entity label.
+func testProviders(cfg config.ServiceConfig, clusters ...logicalcluster.Name) []ProviderWorkspace {
+	providers := make([]ProviderWorkspace, 0, len(clusters))
+	for _, cluster := range clusters {
+		providers = append(providers, ProviderWorkspace{
+			Path:        cluster.Path(),
+			Cluster:     cluster,
+			EntityLabel: cfg.EntityLabel,
+		})
+	}
+	return providers
+}
+
+func newAPIBinding(name, exportName, exportCluster string) unstructured.Unstructured {
+	binding := unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apis.kcp.io/v1alpha1",
+		"kind":       "APIBinding",
+		"metadata": map[string]interface{}{
+			"name": name,
+		},
+		"spec": map[string]interface{}{
+			"reference": map[string]interface{}{
+				"export": map[string]interface{}{
+					"name": exportName,
+				},
+			},
+		},
+		"status": map[string]interface{}{
+			"phase": "Bound",
+		},
+	}}
+	if exportCluster != "" {
+		_ = unstructured.SetNestedField(binding.Object, exportCluster, "status", "apiExportClusterName")
+	}
+	return binding
+}
+
 func TestContentConfigurationLookup_ExcludesContentForFromLocalWorkspace(t *testing.T) {
 	t.Parallel()
 
@@ -152,7 +216,7 @@ func TestContentConfigurationLookup_ExcludesContentForFromLocalWorkspace(t *test
 			storage := &forwardingregistry.StoreFuncs{}
 			storage.ListerFunc = clusterAwareLister(tt.allCCs, accountCluster)
 
-			wrapper := ContentConfigurationLookup(&fakeDynamicClusterClient{}, cfg, "provider-ws")
+			wrapper := ContentConfigurationLookup(&fakeDynamicClusterClient{}, nil, cfg, testProviders(cfg, "provider-ws"), nil)
 			wrapper.Decorate(schema.GroupResource{Group: "ui.platform-mesh.io", Resource: "contentconfigurations"}, storage)
 
 			ctx := WithClusterPath(context.Background(), logicalcluster.NewPath("root:orgs:my-org:my-account"))
@@ -174,6 +238,228 @@ func TestContentConfigurationLookup_ExcludesContentForFromLocalWorkspace(t *test
 	}
 }
 
+func TestContentConfigurationLookup_AppliesCallerSelectorToAllSources(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.NewServiceConfig()
+	accountPath := logicalcluster.NewPath("root:orgs:my-org:my-account")
+	accountCluster := logicalcluster.Name("my-account")
+	exportCluster := logicalcluster.Name("export-ws")
+	providerCluster := logicalcluster.Name("provider-ws")
+
+	withArea := func(name, area string, extra map[string]string) unstructured.Unstructured {
+		ccLabels := map[string]string{"area": area}
+		for k, v := range extra {
+			ccLabels[k] = v
+		}
+		return newCC(name, ccLabels, true)
+	}
+	exportLabels := map[string]string{
+		cfg.ContentForLabel: "openmcp.cloud",
+		cfg.EntityLabel:     cfg.AccountEntityName,
+	}
+	providerLabels := map[string]string{
+		cfg.EntityLabel: cfg.AccountEntityName,
+	}
+
+	storage := &forwardingregistry.StoreFuncs{}
+	storage.ListerFunc = multiClusterLister(map[logicalcluster.Name][]unstructured.Unstructured{
+		accountCluster: {
+			withArea("local-home", "home", nil),
+			withArea("local-settings", "settings", nil),
+		},
+		exportCluster: {
+			withArea("export-home", "home", exportLabels),
+			withArea("export-settings", "settings", exportLabels),
+		},
+		providerCluster: {
+			withArea("provider-home", "home", providerLabels),
+			withArea("provider-settings", "settings", providerLabels),
+		},
+	})
+
+	client := &fakeDynamicClusterClient{
+		objects: map[logicalcluster.Path]map[string][]unstructured.Unstructured{
+			accountPath: {"apibindings": {newAPIBinding("openmcp", "openmcp.cloud", exportCluster.String())}},
+		},
+	}
+
+	wrapper := ContentConfigurationLookup(client, nil, cfg, testProviders(cfg, providerCluster), nil)
+	wrapper.Decorate(schema.GroupResource{Group: "ui.platform-mesh.io", Resource: "contentconfigurations"}, storage)
+
+	ctx := WithClusterPath(context.Background(), accountPath)
+	ctx = genericapirequest.WithCluster(ctx, genericapirequest.Cluster{Name: accountCluster})
+
+	result, err := storage.List(ctx, &internalversion.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{"area": "home"}),
+	})
+	require.NoError(t, err)
+
+	var gotNames []string
+	for _, item := range result.(*unstructured.UnstructuredList).Items {
+		gotNames = append(gotNames, item.GetName())
+	}
+
+	assert.Equal(t, []string{"local-home", "export-home", "provider-home"}, gotNames)
+}
+
+func TestContentConfigurationLookup_ReadsWorkspaceWithUserClient(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.NewServiceConfig()
+	accountPath := logicalcluster.NewPath("root:orgs:my-org:my-account")
+	accountCluster := logicalcluster.Name("my-account")
+
+	exportCC := func(name, exportName string) unstructured.Unstructured {
+		return newCC(name, map[string]string{
+			cfg.ContentForLabel: exportName,
+			cfg.EntityLabel:     cfg.AccountEntityName,
+		}, true)
+	}
+
+	// the service identity sees more than the caller, both in the workspace
+	// itself and in its bindings
+	storage := &forwardingregistry.StoreFuncs{}
+	storage.ListerFunc = multiClusterLister(map[logicalcluster.Name][]unstructured.Unstructured{
+		accountCluster: {newCC("service-local", nil, true)},
+		"export-a":     {exportCC("export-a-home", "a.cloud")},
+		"export-b":     {exportCC("export-b-home", "b.cloud")},
+	})
+	serviceClient := &fakeDynamicClusterClient{
+		objects: map[logicalcluster.Path]map[string][]unstructured.Unstructured{
+			accountPath: {"apibindings": {
+				newAPIBinding("a", "a.cloud", "export-a"),
+				newAPIBinding("b", "b.cloud", "export-b"),
+			}},
+		},
+	}
+	userClient := &fakeDynamicClusterClient{
+		objects: map[logicalcluster.Path]map[string][]unstructured.Unstructured{
+			accountCluster.Path(): {"contentconfigurations": {newCC("user-local", nil, true)}},
+			accountPath:           {"apibindings": {newAPIBinding("a", "a.cloud", "export-a")}},
+		},
+	}
+
+	wrapper := ContentConfigurationLookup(serviceClient, func(ctx context.Context) (kcpdynamic.ClusterInterface, error) {
+		return userClient, nil
+	}, cfg, testProviders(cfg, "provider-ws"), nil)
+	wrapper.Decorate(schema.GroupResource{Group: "ui.platform-mesh.io", Resource: "contentconfigurations"}, storage)
+
+	ctx := WithClusterPath(context.Background(), accountPath)
+	ctx = genericapirequest.WithCluster(ctx, genericapirequest.Cluster{Name: accountCluster})
+
+	result, err := storage.List(ctx, &internalversion.ListOptions{})
+	require.NoError(t, err)
+
+	var gotNames []string
+	for _, item := range result.(*unstructured.UnstructuredList).Items {
+		gotNames = append(gotNames, item.GetName())
+	}
+
+	// the export workspace itself is still read with the service identity
+	assert.Equal(t, []string{"user-local", "export-a-home"}, gotNames)
+}
+
+func TestContentConfigurationLookup_SkipsIncompleteBindings(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.NewServiceConfig()
+	accountPath := logicalcluster.NewPath("root:orgs:my-org:my-account")
+	accountCluster := logicalcluster.Name("my-account")
+
+	exportCC := newCC("export-home", map[string]string{
+		cfg.ContentForLabel: "openmcp.cloud",
+		cfg.EntityLabel:     cfg.AccountEntityName,
+	}, true)
+
+	storage := &forwardingregistry.StoreFuncs{}
+	storage.ListerFunc = multiClusterLister(map[logicalcluster.Name][]unstructured.Unstructured{
+		"export-ws": {exportCC},
+	})
+
+	client := &fakeDynamicClusterClient{
+		objects: map[logicalcluster.Path]map[string][]unstructured.Unstructured{
+			accountPath: {"apibindings": {
+				newAPIBinding("binding", "pending.cloud", ""),
+				newAPIBinding("openmcp", "openmcp.cloud", "export-ws"),
+			}},
+		},
+	}
+
+	wrapper := ContentConfigurationLookup(client, nil, cfg, testProviders(cfg, "provider-ws"), nil)
+	wrapper.Decorate(schema.GroupResource{Group: "ui.platform-mesh.io", Resource: "contentconfigurations"}, storage)
+
+	recorder := &fakeWarningRecorder{}
+	ctx := warning.WithWarningRecorder(context.Background(), recorder)
+	ctx = WithClusterPath(ctx, accountPath)
+	ctx = genericapirequest.WithCluster(ctx, genericapirequest.Cluster{Name: accountCluster})
+
+	result, err := storage.List(ctx, &internalversion.ListOptions{})
+	require.NoError(t, err)
+
+	items := result.(*unstructured.UnstructuredList).Items
+	require.Len(t, items, 1)
+	assert.Equal(t, "export-home", items[0].GetName())
+	require.Len(t, recorder.warnings, 1)
+	assert.Contains(t, recorder.warnings[0], `apibinding "binding"`)
+}
+
+func TestContentConfigurationLookup_InactiveBindings(t *testing.T) {
+	t.Parallel()
+
+	accountPath := logicalcluster.NewPath("root:orgs:my-org:my-account")
+	accountCluster := logicalcluster.Name("my-account")
+
+	for _, includeInactive := range []bool{false, true} {
+		cfg := config.NewServiceConfig()
+		cfg.IncludeInactiveBindings = includeInactive
+
+		storage := &forwardingregistry.StoreFuncs{}
+		storage.ListerFunc = multiClusterLister(map[logicalcluster.Name][]unstructured.Unstructured{
+			"export-ws": {newCC("export-home", map[string]string{
+				cfg.ContentForLabel: "openmcp.cloud",
+				cfg.EntityLabel:     cfg.AccountEntityName,
+			}, true)},
+		})
+
+		binding := newAPIBinding("openmcp", "openmcp.cloud", "export-ws")
+		require.NoError(t, unstructured.SetNestedField(binding.Object, "Binding", "status", "phase"))
+
+		client := &fakeDynamicClusterClient{
+			objects: map[logicalcluster.Path]map[string][]unstructured.Unstructured{
+				accountPath: {"apibindings": {binding}},
+			},
+		}
+
+		wrapper := ContentConfigurationLookup(client, nil, cfg, testProviders(cfg, "provider-ws"), nil)
+		wrapper.Decorate(schema.GroupResource{Group: "ui.platform-mesh.io", Resource: "contentconfigurations"}, storage)
+
+		ctx := WithClusterPath(context.Background(), accountPath)
+		ctx = genericapirequest.WithCluster(ctx, genericapirequest.Cluster{Name: accountCluster})
+
+		result, err := storage.List(ctx, &internalversion.ListOptions{})
+		require.NoError(t, err)
+
+		items := result.(*unstructured.UnstructuredList).Items
+		if !includeInactive {
+			assert.Empty(t, items)
+			continue
+		}
+
+		require.Len(t, items, 1)
+		assert.Equal(t, "true", items[0].GetAnnotations()[InactiveAnnotation])
+		assert.Equal(t, "binding is in phase Binding", items[0].GetAnnotations()[InactiveReasonAnnotation])
+	}
+}
+
+type fakeWarningRecorder struct {
+	warnings []string
+}
+
+func (f *fakeWarningRecorder) AddWarning(_, text string) {
+	f.warnings = append(f.warnings, text)
+}
+
 func TestContentConfigurationWithResult(t *testing.T) {
 	t.Parallel()
 
@@ -223,3 +509,44 @@ func TestContentConfigurationWithResult(t *testing.T) {
 		})
 	}
 }
+
+func TestContentConfigurationLookup_MergesProviderWorkspaces(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.NewServiceConfig()
+	accountPath := logicalcluster.NewPath("root:orgs:my-org:my-account")
+	accountCluster := logicalcluster.Name("my-account")
+
+	storage := &forwardingregistry.StoreFuncs{}
+	storage.ListerFunc = multiClusterLister(map[logicalcluster.Name][]unstructured.Unstructured{
+		"core-ws": {
+			newCC("core-home", map[string]string{cfg.EntityLabel: cfg.AccountEntityName}, true),
+			newCC("core-org", map[string]string{cfg.EntityLabel: cfg.MainEntityName}, true),
+		},
+		"billing-ws": {
+			newCC("billing-default-label", map[string]string{cfg.EntityLabel: cfg.AccountEntityName}, true),
+			newCC("billing-invoices", map[string]string{"billing.io/entity": cfg.AccountEntityName}, true),
+		},
+	})
+
+	providers := []ProviderWorkspace{
+		{Path: logicalcluster.NewPath("root:core"), Cluster: "core-ws", EntityLabel: cfg.EntityLabel},
+		{Path: logicalcluster.NewPath("root:billing"), Cluster: "billing-ws", EntityLabel: "billing.io/entity"},
+	}
+
+	wrapper := ContentConfigurationLookup(&fakeDynamicClusterClient{}, nil, cfg, providers, nil)
+	wrapper.Decorate(schema.GroupResource{Group: "ui.platform-mesh.io", Resource: "contentconfigurations"}, storage)
+
+	ctx := WithClusterPath(context.Background(), accountPath)
+	ctx = genericapirequest.WithCluster(ctx, genericapirequest.Cluster{Name: accountCluster})
+
+	result, err := storage.List(ctx, &internalversion.ListOptions{})
+	require.NoError(t, err)
+
+	var gotNames []string
+	for _, item := range result.(*unstructured.UnstructuredList).Items {
+		gotNames = append(gotNames, item.GetName())
+	}
+
+	assert.Equal(t, []string{"core-home", "billing-invoices"}, gotNames)
+}
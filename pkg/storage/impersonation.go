@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kcp-dev/client-go/dynamic"
+	"github.com/kcp-dev/virtual-workspace-framework/pkg/forwardingregistry"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/client-go/rest"
+
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+)
+
+// ImpersonatingClusterClient returns a client func acting as the user of the
+// request, so kcp authorizes the request for the caller instead of the virtual
+// workspace. The identity of restCfg needs the permission to impersonate users.
+func ImpersonatingClusterClient(restCfg *rest.Config) forwardingregistry.DynamicClusterClientFunc {
+	return func(ctx context.Context) (dynamic.ClusterInterface, error) {
+		cfg, err := impersonationConfigFor(ctx, restCfg)
+		if err != nil {
+			return nil, err
+		}
+
+		client, err := dynamic.NewForConfig(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create impersonating client: %w", err)
+		}
+
+		return client, nil
+	}
+}
+
+// impersonationConfigFor returns a copy of restCfg impersonating the user of the
+// request. Anonymous requests are rejected, they have no identity to act as.
+func impersonationConfigFor(ctx context.Context, restCfg *rest.Config) (*rest.Config, error) {
+	u, ok := genericapirequest.UserFrom(ctx)
+	if !ok || u.GetName() == "" || u.GetName() == user.Anonymous {
+		return nil, kerrors.NewUnauthorized("the request has no identified user to act as")
+	}
+
+	cfg := rest.CopyConfig(restCfg)
+	cfg.Impersonate = rest.ImpersonationConfig{
+		UserName: u.GetName(),
+		UID:      u.GetUID(),
+		Groups:   u.GetGroups(),
+		Extra:    u.GetExtra(),
+	}
+
+	return cfg, nil
+}
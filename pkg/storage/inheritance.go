@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/kcp-dev/client-go/dynamic"
+	"github.com/kcp-dev/logicalcluster/v3"
+	"github.com/platform-mesh/virtual-workspaces/pkg/config"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/util/cache"
+)
+
+// InheritedFromAnnotation is set on contentconfigurations inherited from an
+// ancestor workspace and holds the path of that workspace.
+const InheritedFromAnnotation = "ui.platform-mesh.io/inherited-from"
+
+// ClusterNameResolver resolves a workspace path to the name of its logical
+// cluster, which contentconfiguration sources and the cache are keyed by.
+type ClusterNameResolver func(ctx context.Context, path logicalcluster.Path) (logicalcluster.Name, error)
+
+// NewClusterNameResolver returns a resolver reading the LogicalCluster of the
+// workspace, like proxy.NewClusterResolver does for requests. Resolved names
+// are cached per path.
+func NewClusterNameResolver(client dynamic.ClusterInterface) ClusterNameResolver {
+	names := cache.NewLRUExpireCache(workspaceCacheSize)
+
+	return func(ctx context.Context, path logicalcluster.Path) (logicalcluster.Name, error) {
+		if name, isName := path.Name(); isName {
+			return name, nil
+		}
+		if cached, ok := names.Get(path.String()); ok {
+			return cached.(logicalcluster.Name), nil
+		}
+
+		lc, err := client.Cluster(path).Resource(logicalClusterGVR).Get(ctx, "cluster", metav1.GetOptions{})
+		if err != nil {
+			return "", err
+		}
+
+		name := logicalcluster.From(lc)
+		names.Add(path.String(), name, workspaceCacheTTL)
+		return name, nil
+	}
+}
+
+// ancestorSources returns a source for every ancestor of path, nearest first.
+// Only contentconfigurations labeled as inheritable for the requested entity
+// type are collected; projected ones are left to their export workspaces.
+// Ancestors that cannot be resolved to a logical cluster are skipped.
+func ancestorSources(
+	ctx context.Context,
+	cfg config.ServiceConfig,
+	path logicalcluster.Path,
+	entityType string,
+	callerReqs labels.Requirements,
+	resolveClusterName ClusterNameResolver,
+) ([]contentSource, error) {
+	noContentFor, err := labels.NewRequirement(cfg.ContentForLabel, selection.DoesNotExist, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var sources []contentSource
+	for ancestor, ok := path.Parent(); ok; ancestor, ok = ancestor.Parent() {
+		cluster, err := resolveClusterName(ctx, ancestor)
+		if err != nil {
+			skipContentSource(ctx, "ancestor", ancestor.String(), skipReasonUnresolvable, err)
+			continue
+		}
+
+		sources = append(sources, contentSource{
+			kind:    "ancestor",
+			name:    ancestor.String(),
+			cluster: cluster,
+			selector: labels.SelectorFromValidatedSet(map[string]string{
+				cfg.InheritableLabel: "true",
+				cfg.EntityLabel:      entityType,
+			}).Add(*noContentFor).Add(callerReqs...),
+			optional: true,
+		})
+	}
+
+	return sources, nil
+}
+
+// mergeInherited appends the contentconfigurations of the ancestor sources to
+// the local ones. The nearest definition of a name wins, so local items override
+// inherited ones and nearer ancestors override those further up.
+func mergeInherited(ctx context.Context, local []unstructured.Unstructured, sources []contentSource, sourceItems [][]unstructured.Unstructured) []unstructured.Unstructured {
+	seen := make(map[string]bool, len(local))
+	for _, item := range local {
+		seen[item.GetName()] = true
+	}
+
+	for i, source := range sources {
+		for _, item := range sourceItems[i] {
+			if seen[item.GetName()] {
+				diagnosticsFrom(ctx).exclude(source, item.GetName(), reasonOverridden)
+				continue
+			}
+			seen[item.GetName()] = true
+
+			annotations := item.GetAnnotations()
+			if annotations == nil {
+				annotations = map[string]string{}
+			}
+			annotations[InheritedFromAnnotation] = source.name
+			item.SetAnnotations(annotations)
+
+			local = append(local, item)
+		}
+	}
+
+	return local
+}
@@ -0,0 +1,149 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+	"github.com/kcp-dev/virtual-workspace-framework/pkg/forwardingregistry"
+	"github.com/platform-mesh/virtual-workspaces/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/apis/meta/internalversion"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+)
+
+func TestContentConfigurationLookup_InheritsFromAncestors(t *testing.T) {
+	t.Parallel()
+
+	teamPath := logicalcluster.NewPath("root:orgs:acme:team-a")
+	teamCluster := logicalcluster.Name("team-a")
+
+	// ancestors are listed by their logical cluster name, not their path
+	ancestorClusters := map[logicalcluster.Path]logicalcluster.Name{
+		logicalcluster.NewPath("root:orgs:acme"): "2x8fkq3lm1acme",
+		logicalcluster.NewPath("root:orgs"):      "1ndhs7z0orgs",
+	}
+	client := &fakeDynamicClusterClient{objects: map[logicalcluster.Path]map[string][]unstructured.Unstructured{}}
+	for path, cluster := range ancestorClusters {
+		lc := newLogicalCluster("")
+		lc.SetAnnotations(map[string]string{logicalcluster.AnnotationKey: cluster.String()})
+		client.objects[path] = map[string][]unstructured.Unstructured{"logicalclusters": {lc}}
+	}
+
+	newInheritable := func(cfg config.ServiceConfig, name string) unstructured.Unstructured {
+		return newCC(name, map[string]string{
+			cfg.InheritableLabel: "true",
+			cfg.EntityLabel:      cfg.AccountEntityName,
+		}, true)
+	}
+
+	tests := []struct {
+		name              string
+		inherit           bool
+		expectedNames     []string
+		expectedInherited map[string]string
+	}{
+		{
+			name:          "nearest definition wins",
+			inherit:       true,
+			expectedNames: []string{"overridden", "shared-nav", "org-wide"},
+			expectedInherited: map[string]string{
+				"shared-nav": "root:orgs:acme",
+				"org-wide":   "root:orgs",
+			},
+		},
+		{
+			name:          "ancestors are ignored when inheritance is disabled",
+			inherit:       false,
+			expectedNames: []string{"overridden"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := config.NewServiceConfig()
+			cfg.InheritContentConfigurations = tt.inherit
+
+			storage := &forwardingregistry.StoreFuncs{}
+			storage.ListerFunc = multiClusterLister(map[logicalcluster.Name][]unstructured.Unstructured{
+				teamCluster: {newCC("overridden", nil, true)},
+				"2x8fkq3lm1acme": {
+					newInheritable(cfg, "shared-nav"),
+					newInheritable(cfg, "overridden"),
+					newCC("acme-only", map[string]string{cfg.EntityLabel: cfg.AccountEntityName}, true),
+					newCC("projected", map[string]string{
+						cfg.InheritableLabel: "true",
+						cfg.EntityLabel:      cfg.AccountEntityName,
+						cfg.ContentForLabel:  "openmcp.cloud",
+					}, true),
+				},
+				"1ndhs7z0orgs": {
+					newInheritable(cfg, "shared-nav"),
+					newInheritable(cfg, "org-wide"),
+				},
+			})
+
+			wrapper := ContentConfigurationLookup(client, nil, cfg, testProviders(cfg, "provider-ws"), nil)
+			wrapper.Decorate(schema.GroupResource{Group: "ui.platform-mesh.io", Resource: "contentconfigurations"}, storage)
+
+			ctx := WithClusterPath(context.Background(), teamPath)
+			ctx = genericapirequest.WithCluster(ctx, genericapirequest.Cluster{Name: teamCluster})
+
+			result, err := storage.List(ctx, &internalversion.ListOptions{})
+			require.NoError(t, err)
+
+			var gotNames []string
+			gotInherited := map[string]string{}
+			for _, item := range result.(*unstructured.UnstructuredList).Items {
+				gotNames = append(gotNames, item.GetName())
+				if from, ok := item.GetAnnotations()[InheritedFromAnnotation]; ok {
+					gotInherited[item.GetName()] = from
+				}
+			}
+
+			assert.Equal(t, tt.expectedNames, gotNames)
+			if tt.expectedInherited == nil {
+				assert.Empty(t, gotInherited)
+			} else {
+				assert.Equal(t, tt.expectedInherited, gotInherited)
+			}
+		})
+	}
+}
+
+func TestClusterNameResolver(t *testing.T) {
+	t.Parallel()
+
+	acmePath := logicalcluster.NewPath("root:orgs:acme")
+	lc := newLogicalCluster("")
+	lc.SetAnnotations(map[string]string{logicalcluster.AnnotationKey: "2x8fkq3lm1acme"})
+	client := &fakeDynamicClusterClient{objects: map[logicalcluster.Path]map[string][]unstructured.Unstructured{
+		acmePath: {"logicalclusters": {lc}},
+	}}
+	resolve := NewClusterNameResolver(client)
+
+	name, err := resolve(context.Background(), acmePath)
+	require.NoError(t, err)
+	assert.Equal(t, logicalcluster.Name("2x8fkq3lm1acme"), name)
+
+	// served from the cache
+	delete(client.objects, acmePath)
+	name, err = resolve(context.Background(), acmePath)
+	require.NoError(t, err)
+	assert.Equal(t, logicalcluster.Name("2x8fkq3lm1acme"), name)
+
+	// single segment paths are cluster names already
+	name, err = resolve(context.Background(), logicalcluster.NewPath("root"))
+	require.NoError(t, err)
+	assert.Equal(t, logicalcluster.Name("root"), name)
+
+	_, err = resolve(context.Background(), logicalcluster.NewPath("root:orgs:unknown"))
+	require.Error(t, err)
+}
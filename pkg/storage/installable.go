@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/kcp-dev/kcp/pkg/authorization/delegated"
+	"github.com/kcp-dev/logicalcluster/v3"
+	apisv1alpha1 "github.com/kcp-dev/sdk/apis/apis/v1alpha1"
+	"github.com/platform-mesh/virtual-workspaces/pkg/authorization"
+
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/klog/v2"
+)
+
+// installChecker decides whether the caller of a marketplace request may
+// install APIExports into the requested workspace, i.e. create APIBindings
+// there and bind the APIExport in its own workspace.
+type installChecker struct {
+	authz delegated.Cache
+	user  user.Info
+
+	canCreateBindings bool
+}
+
+func newInstallChecker(ctx context.Context, authz delegated.Cache, cluster logicalcluster.Name) (*installChecker, error) {
+	attributes, err := authorization.AttributesFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &installChecker{
+		authz: authz,
+		user:  attributes.GetUser(),
+	}
+
+	c.canCreateBindings = c.allowed(ctx, cluster, authorizer.AttributesRecord{
+		User:            c.user,
+		Verb:            "create",
+		APIGroup:        apisv1alpha1.SchemeGroupVersion.Group,
+		APIVersion:      "*",
+		Resource:        "apibindings",
+		ResourceRequest: true,
+	})
+
+	return c, nil
+}
+
+// canInstall reports whether the caller may bind export. Failed checks are
+// logged and treated as not allowed, so they do not fail the whole catalog.
+func (c *installChecker) canInstall(ctx context.Context, export *apisv1alpha1.APIExport) bool {
+	if !c.canCreateBindings {
+		return false
+	}
+
+	return c.allowed(ctx, logicalcluster.From(export), authorizer.AttributesRecord{
+		User:            c.user,
+		Verb:            "bind",
+		APIGroup:        apisv1alpha1.SchemeGroupVersion.Group,
+		APIVersion:      "*",
+		Resource:        "apiexports",
+		Name:            export.Name,
+		ResourceRequest: true,
+	})
+}
+
+func (c *installChecker) allowed(ctx context.Context, cluster logicalcluster.Name, attr authorizer.AttributesRecord) bool {
+	if cluster.Empty() {
+		return false
+	}
+
+	authz, err := c.authz.Get(cluster)
+	if err != nil {
+		klog.ErrorS(err, "failed to get authorizer", "cluster", cluster)
+		return false
+	}
+
+	decision, _, err := authz.Authorize(ctx, attr)
+	if err != nil {
+		klog.ErrorS(err, "failed to check marketplace install permission", "cluster", cluster, "verb", attr.Verb, "resource", attr.Resource, "name", attr.Name)
+		return false
+	}
+
+	return decision == authorizer.DecisionAllow
+}
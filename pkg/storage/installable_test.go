@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+	apisv1alpha1 "github.com/kcp-dev/sdk/apis/apis/v1alpha1"
+	"github.com/platform-mesh/virtual-workspaces/pkg/authorization"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+)
+
+// fakeWorkspaceAuthorizers allows the requests listed per workspace as
+// "verb resource/name" and fails for workspaces in errs.
+type fakeWorkspaceAuthorizers struct {
+	allowed map[logicalcluster.Name][]string
+	errs    map[logicalcluster.Name]error
+}
+
+func (f *fakeWorkspaceAuthorizers) Get(cluster logicalcluster.Name) (authorizer.Authorizer, error) {
+	return authorizer.AuthorizerFunc(func(ctx context.Context, a authorizer.Attributes) (authorizer.Decision, string, error) {
+		if err := f.errs[cluster]; err != nil {
+			return authorizer.DecisionNoOpinion, "", err
+		}
+		if a.GetUser().GetName() != "alice" {
+			return authorizer.DecisionNoOpinion, "", nil
+		}
+
+		request := a.GetVerb() + " " + a.GetResource()
+		if a.GetName() != "" {
+			request += "/" + a.GetName()
+		}
+		for _, allowed := range f.allowed[cluster] {
+			if allowed == request {
+				return authorizer.DecisionAllow, "", nil
+			}
+		}
+		return authorizer.DecisionNoOpinion, "", nil
+	}), nil
+}
+
+func TestInstallChecker(t *testing.T) {
+	t.Parallel()
+
+	export := func(cluster, name string) *apisv1alpha1.APIExport {
+		return &apisv1alpha1.APIExport{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Annotations: map[string]string{logicalcluster.AnnotationKey: cluster},
+			},
+		}
+	}
+
+	authz := &fakeWorkspaceAuthorizers{
+		allowed: map[logicalcluster.Name][]string{
+			"consumer":        {"create apibindings"},
+			"other-consumer":  {},
+			"provider":        {"bind apiexports/public"},
+			"broken-provider": {"bind apiexports/public"},
+		},
+		errs: map[logicalcluster.Name]error{
+			"broken-provider": errors.New("connection refused"),
+		},
+	}
+
+	tests := []struct {
+		name     string
+		user     string
+		cluster  logicalcluster.Name
+		export   *apisv1alpha1.APIExport
+		expected bool
+	}{
+		{name: "may create bindings and bind the export", user: "alice", cluster: "consumer", export: export("provider", "public"), expected: true},
+		{name: "may not bind the export", user: "alice", cluster: "consumer", export: export("provider", "private"), expected: false},
+		{name: "may not create bindings", user: "alice", cluster: "other-consumer", export: export("provider", "public"), expected: false},
+		{name: "other user", user: "bob", cluster: "consumer", export: export("provider", "public"), expected: false},
+		{name: "failed checks are not allowed", user: "alice", cluster: "consumer", export: export("broken-provider", "public"), expected: false},
+		{name: "export without workspace", user: "alice", cluster: "consumer", export: export("", "public"), expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := authorization.WithAttributeHolder(context.Background())
+			authorization.UpdateAttributes(ctx, authorizer.AttributesRecord{
+				User: &user.DefaultInfo{Name: tt.user, Groups: []string{user.AllAuthenticated}},
+			})
+
+			checker, err := newInstallChecker(ctx, authz, tt.cluster)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, checker.canInstall(ctx, tt.export))
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/kcp-dev/client-go/dynamic"
+	"github.com/kcp-dev/virtual-workspace-framework/pkg/forwardingregistry"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/internalversion"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+)
+
+var ContentConfigurationGVR = schema.GroupVersionResource{
+	Group:    "ui.platform-mesh.io",
+	Version:  "v1alpha1",
+	Resource: "contentconfigurations",
+}
+
+var apiBindingGVR = schema.GroupVersionResource{
+	Group:    "apis.kcp.io",
+	Version:  "v1alpha1",
+	Resource: "apibindings",
+}
+
+// dynamicLister lists the given resource in the cluster of the request context.
+// It is the equivalent of the forwarding storage's lister for callers that do
+// not own a storage for the resource.
+func dynamicLister(client dynamic.ClusterInterface, gvr schema.GroupVersionResource) forwardingregistry.ListerFunc {
+	return func(ctx context.Context, options *internalversion.ListOptions) (runtime.Object, error) {
+		cluster := genericapirequest.ClusterFrom(ctx)
+		if cluster == nil {
+			return nil, kerrors.NewBadRequest("cluster not found in context")
+		}
+
+		var v1Opts metav1.ListOptions
+		if err := internalversion.Convert_internalversion_ListOptions_To_v1_ListOptions(options, &v1Opts, nil); err != nil {
+			return nil, err
+		}
+
+		return client.Cluster(cluster.Name.Path()).Resource(gvr).List(ctx, v1Opts)
+	}
+}
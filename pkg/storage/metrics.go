@@ -0,0 +1,35 @@
+package storage
+
+import (
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+const metricsSubsystem = "contentconfigurations"
+
+var skippedContentSources = metrics.NewCounterVec(
+	&metrics.CounterOpts{
+		Subsystem:      metricsSubsystem,
+		Name:           "skipped_sources_total",
+		Help:           "Number of contentconfiguration sources skipped while serving a list request, by source kind and reason.",
+		StabilityLevel: metrics.ALPHA,
+	},
+	[]string{"source", "reason"},
+)
+
+// rateLimitedRequests is shared by all virtual workspaces, hence not in the
+// contentconfigurations subsystem.
+var rateLimitedRequests = metrics.NewCounterVec(
+	&metrics.CounterOpts{
+		Subsystem:      "tenant_limiter",
+		Name:           "rate_limited_requests_total",
+		Help:           "Number of requests rejected by the per user and logical cluster limits, by limit and requested resource.",
+		StabilityLevel: metrics.ALPHA,
+	},
+	[]string{"limit", "resource"},
+)
+
+func init() {
+	legacyregistry.MustRegister(skippedContentSources)
+	legacyregistry.MustRegister(rateLimitedRequests)
+}
@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+	"github.com/kcp-dev/virtual-workspace-framework/pkg/dynamic/apiserver"
+	registry "github.com/kcp-dev/virtual-workspace-framework/pkg/forwardingregistry"
+	uiv1alpha1 "github.com/platform-mesh/virtual-workspaces/api/ui/v1alpha1"
+	"k8s.io/apimachinery/pkg/apis/meta/internalversion"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog/v2"
+)
+
+// CreateNavigationStorageProviderFunc returns a storage provider for the
+// read-only contentnavigations resource. Its single object is compiled on every
+// request from the contentconfigurations returned by lister.
+func CreateNavigationStorageProviderFunc(lister registry.ListerFunc) func(ctx context.Context) (apiserver.RestProviderFunc, error) {
+	return createSingletonStorageProviderFunc(uiv1alpha1.ContentNavigationName, func(ctx context.Context, options *internalversion.ListOptions) (any, error) {
+		result, err := lister(ctx, options)
+		if err != nil {
+			return nil, err
+		}
+
+		return compileNavigation(result.(*unstructured.UnstructuredList).Items), nil
+	})
+}
+
+// compileNavigation merges the navigation nodes of all contentconfigurations into
+// a single document. Nodes are de-duplicated by entity type and path segment,
+// where the first contentconfiguration in the given order wins, and sorted by
+// their order.
+func compileNavigation(ccs []unstructured.Unstructured) *uiv1alpha1.ContentNavigation {
+	navigation := &uiv1alpha1.ContentNavigation{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: uiv1alpha1.ContentNavigationName,
+		},
+	}
+
+	seen := map[string]bool{}
+	var nodes []map[string]interface{}
+	for _, cc := range ccs {
+		// inactive contentconfigurations are only listed for diagnostics and
+		// must not end up in the navigation
+		if cc.GetAnnotations()[InactiveAnnotation] == "true" {
+			continue
+		}
+
+		ccNodes, err := navigationNodes(cc)
+		if err != nil {
+			klog.V(4).InfoS("skipping contentconfiguration with unparsable configurationResult", "cc", cc.GetName(), "err", err)
+			continue
+		}
+
+		navigation.Spec.Sources = append(navigation.Spec.Sources, uiv1alpha1.ContentNavigationSource{
+			Name:    cc.GetName(),
+			Cluster: logicalcluster.From(&cc).String(),
+		})
+
+		for _, node := range ccNodes {
+			key := nodeKey(node)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			nodes = append(nodes, node)
+		}
+	}
+
+	slices.SortStableFunc(nodes, func(a, b map[string]interface{}) int {
+		return cmp.Compare(nodeOrder(a), nodeOrder(b))
+	})
+
+	for _, node := range nodes {
+		raw, err := json.Marshal(node)
+		if err != nil {
+			continue
+		}
+		navigation.Spec.Nodes = append(navigation.Spec.Nodes, uiv1alpha1.Node{RawExtension: runtime.RawExtension{Raw: raw}})
+	}
+
+	return navigation
+}
+
+// navigationNodes extracts the nodes of a contentconfiguration's
+// configurationResult, which is either a JSON document or an object.
+func navigationNodes(cc unstructured.Unstructured) ([]map[string]interface{}, error) {
+	result, _, err := unstructured.NestedFieldNoCopy(cc.Object, "status", "configurationResult")
+	if err != nil {
+		return nil, err
+	}
+
+	if raw, ok := result.(string); ok {
+		var parsed map[string]interface{}
+		if err := json.Unmarshal([]byte(raw), &parsed); err != nil {
+			return nil, err
+		}
+		result = parsed
+	}
+
+	document, ok := result.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected configurationResult type %T", result)
+	}
+
+	rawNodes, found, err := unstructured.NestedFieldNoCopy(document, "luigiConfigFragment", "data", "nodes")
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		rawNodes = document["nodes"]
+	}
+
+	list, _ := rawNodes.([]interface{})
+	nodes := make([]map[string]interface{}, 0, len(list))
+	for _, rawNode := range list {
+		if node, ok := rawNode.(map[string]interface{}); ok {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes, nil
+}
+
+func nodeKey(node map[string]interface{}) string {
+	entityType, _ := node["entityType"].(string)
+	pathSegment, _ := node["pathSegment"].(string)
+	if pathSegment != "" {
+		return entityType + "/" + pathSegment
+	}
+
+	// nodes without a path segment are only de-duplicated if identical
+	raw, _ := json.Marshal(node)
+	return string(raw)
+}
+
+// nodeOrder returns the numeric order of a node, which is defined either as a
+// number or as a numeric string. Nodes without an order are sorted last.
+func nodeOrder(node map[string]interface{}) float64 {
+	switch order := node["order"].(type) {
+	case float64:
+		return order
+	case int64:
+		return float64(order)
+	case string:
+		if parsed, err := strconv.ParseFloat(order, 64); err == nil {
+			return parsed
+		}
+	}
+	return float64(1 << 53)
+}
@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newCCWithNodes(t *testing.T, name, cluster string, nodes ...map[string]interface{}) unstructured.Unstructured {
+	t.Helper()
+
+	rawNodes := make([]interface{}, 0, len(nodes))
+	for _, node := range nodes {
+		rawNodes = append(rawNodes, node)
+	}
+
+	result, err := json.Marshal(map[string]interface{}{
+		"name": name,
+		"luigiConfigFragment": map[string]interface{}{
+			"data": map[string]interface{}{
+				"nodes": rawNodes,
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	cc := newCC(name, nil, false)
+	cc.SetAnnotations(map[string]string{"kcp.io/cluster": cluster})
+	require.NoError(t, unstructured.SetNestedField(cc.Object, string(result), "status", "configurationResult"))
+	return cc
+}
+
+func TestCompileNavigation(t *testing.T) {
+	t.Parallel()
+
+	ccs := []unstructured.Unstructured{
+		newCCWithNodes(t, "local", "account-ws",
+			map[string]interface{}{"pathSegment": "settings", "entityType": "main", "order": "900"},
+			map[string]interface{}{"pathSegment": "home", "entityType": "main", "order": 1, "label": "Local Home"},
+		),
+		newCCWithNodes(t, "provider", "provider-ws",
+			map[string]interface{}{"pathSegment": "home", "entityType": "main", "order": "1", "label": "Provider Home"},
+			map[string]interface{}{"pathSegment": "docs", "entityType": "main"},
+			map[string]interface{}{"pathSegment": "home", "entityType": "main.account", "order": "50"},
+		),
+		newCC("legacy", nil, true),
+	}
+
+	navigation := compileNavigation(ccs)
+
+	var got []map[string]interface{}
+	for _, node := range navigation.Spec.Nodes {
+		var decoded map[string]interface{}
+		require.NoError(t, json.Unmarshal(node.Raw, &decoded))
+		got = append(got, decoded)
+	}
+
+	require.Len(t, got, 4)
+	assert.Equal(t, "Local Home", got[0]["label"])
+	assert.Equal(t, "main.account", got[1]["entityType"])
+	assert.Equal(t, "settings", got[2]["pathSegment"])
+	assert.Equal(t, "docs", got[3]["pathSegment"])
+
+	var sources []string
+	for _, source := range navigation.Spec.Sources {
+		sources = append(sources, source.Cluster+"/"+source.Name)
+	}
+	assert.Equal(t, []string{"account-ws/local", "provider-ws/provider", "/legacy"}, sources)
+}
@@ -0,0 +1,241 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/kcp-dev/virtual-workspace-framework/pkg/forwardingregistry"
+	"github.com/platform-mesh/virtual-workspaces/pkg/config"
+	"golang.org/x/time/rate"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/internalversion"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/registry/rest"
+	"k8s.io/utils/clock"
+
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+)
+
+const (
+	// tenantLimiterSize bounds the number of tenants whose buckets are kept,
+	// tenantLimiterTTL how long the buckets of an idle tenant are kept.
+	tenantLimiterSize = 8192
+	tenantLimiterTTL  = 10 * time.Minute
+
+	limitNameRequests = "requests"
+	limitNameFanOut   = "fanout"
+)
+
+// TenantLimiter limits the requests of every tenant, a user in a logical
+// cluster, with token buckets, so a single tenant cannot overload kcp for the
+// others. Requests are limited by count, and contentconfiguration lists
+// additionally by the number of workspaces they fan out to.
+type TenantLimiter struct {
+	requestLimit, fanOutLimit rate.Limit
+	requestBurst, fanOutBurst int
+
+	clock clock.PassiveClock
+
+	lock    sync.Mutex
+	buckets *cache.LRUExpireCache
+}
+
+type tenantBuckets struct {
+	requests *rate.Limiter
+	fanOut   *rate.Limiter
+	clock    clock.PassiveClock
+}
+
+type tenantBucketsKey struct{}
+
+// NewTenantLimiter returns a limiter for the tenant limits of cfg.
+func NewTenantLimiter(cfg config.ServiceConfig) *TenantLimiter {
+	return newTenantLimiter(cfg, clock.RealClock{})
+}
+
+func newTenantLimiter(cfg config.ServiceConfig, clk clock.Clock) *TenantLimiter {
+	limit := func(qps float64) rate.Limit {
+		if qps <= 0 {
+			return rate.Inf
+		}
+		return rate.Limit(qps)
+	}
+
+	return &TenantLimiter{
+		requestLimit: limit(cfg.TenantRequestQPS),
+		requestBurst: max(cfg.TenantRequestBurst, 1),
+		fanOutLimit:  limit(cfg.TenantFanOutQPS),
+		fanOutBurst:  max(cfg.TenantFanOutBurst, 1),
+		clock:        clk,
+		buckets:      cache.NewLRUExpireCacheWithClock(tenantLimiterSize, clk),
+	}
+}
+
+// Wrapper limits the gets, lists and watches of a storage. It has to be applied
+// after wrappers replacing these funcs, e.g. ContentConfigurationLookup.
+func (l *TenantLimiter) Wrapper() forwardingregistry.StorageWrapper {
+	return forwardingregistry.StorageWrapperFunc(func(resource schema.GroupResource, storage *forwardingregistry.StoreFuncs) {
+		get := storage.GetterFunc
+		storage.GetterFunc = func(ctx context.Context, name string, options *metav1.GetOptions) (runtime.Object, error) {
+			ctx, err := l.admit(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return get(ctx, name, options)
+		}
+		storage.ListerFunc = l.Lister(storage.ListerFunc)
+		if watcher := storage.WatcherFunc; watcher != nil {
+			storage.WatcherFunc = func(ctx context.Context, options *internalversion.ListOptions) (watch.Interface, error) {
+				ctx, err := l.admit(ctx)
+				if err != nil {
+					return nil, err
+				}
+				return watcher(ctx, options)
+			}
+		}
+	})
+}
+
+// WriteWrapper limits the creates, updates and deletes of a storage. Their
+// gets, e.g. of updates reading the current object, are not limited again.
+// It has to be applied after wrappers replacing these funcs, e.g. by Writer.
+func (l *TenantLimiter) WriteWrapper() forwardingregistry.StorageWrapper {
+	return forwardingregistry.StorageWrapperFunc(func(resource schema.GroupResource, storage *forwardingregistry.StoreFuncs) {
+		if create := storage.CreaterFunc; create != nil {
+			storage.CreaterFunc = func(ctx context.Context, obj runtime.Object, createValidation rest.ValidateObjectFunc, options *metav1.CreateOptions) (runtime.Object, error) {
+				ctx, err := l.admit(ctx)
+				if err != nil {
+					return nil, err
+				}
+				return create(ctx, obj, createValidation, options)
+			}
+		}
+		if update := storage.UpdaterFunc; update != nil {
+			storage.UpdaterFunc = func(ctx context.Context, name string, objInfo rest.UpdatedObjectInfo, createValidation rest.ValidateObjectFunc, updateValidation rest.ValidateObjectUpdateFunc, forceAllowCreate bool, options *metav1.UpdateOptions) (runtime.Object, bool, error) {
+				ctx, err := l.admit(ctx)
+				if err != nil {
+					return nil, false, err
+				}
+				return update(ctx, name, objInfo, createValidation, updateValidation, forceAllowCreate, options)
+			}
+		}
+		if gracefulDelete := storage.GracefulDeleterFunc; gracefulDelete != nil {
+			storage.GracefulDeleterFunc = func(ctx context.Context, name string, deleteValidation rest.ValidateObjectFunc, options *metav1.DeleteOptions) (runtime.Object, bool, error) {
+				ctx, err := l.admit(ctx)
+				if err != nil {
+					return nil, false, err
+				}
+				return gracefulDelete(ctx, name, deleteValidation, options)
+			}
+		}
+	})
+}
+
+// Lister limits the requests of lister, for listers served outside of a
+// forwarding storage.
+func (l *TenantLimiter) Lister(lister forwardingregistry.ListerFunc) forwardingregistry.ListerFunc {
+	return func(ctx context.Context, options *internalversion.ListOptions) (runtime.Object, error) {
+		ctx, err := l.admit(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return lister(ctx, options)
+	}
+}
+
+// admit takes a request token of the tenant and returns a context carrying its
+// buckets for the limits checked while serving the request.
+func (l *TenantLimiter) admit(ctx context.Context) (context.Context, error) {
+	if l == nil {
+		return ctx, nil
+	}
+
+	buckets := l.bucketsFor(ctx)
+	if err := take(ctx, buckets.requests, 1, l.clock.Now(), limitNameRequests); err != nil {
+		return nil, err
+	}
+
+	return context.WithValue(ctx, tenantBucketsKey{}, buckets), nil
+}
+
+func (l *TenantLimiter) bucketsFor(ctx context.Context) *tenantBuckets {
+	key := tenantKey(ctx)
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if cached, ok := l.buckets.Get(key); ok {
+		return cached.(*tenantBuckets)
+	}
+
+	buckets := &tenantBuckets{
+		requests: rate.NewLimiter(l.requestLimit, l.requestBurst),
+		fanOut:   rate.NewLimiter(l.fanOutLimit, l.fanOutBurst),
+		clock:    l.clock,
+	}
+	l.buckets.Add(key, buckets, tenantLimiterTTL)
+	return buckets
+}
+
+// TokenHashExtraKey is the user extra carrying the hash of the token of a
+// caller that was authenticated, but could not be identified. Such callers all
+// share the anonymous name, so they are told apart by their token instead.
+const TokenHashExtraKey = "ui.platform-mesh.io/token-hash"
+
+// tenantKey identifies the tenant of a request by user and logical cluster.
+func tenantKey(ctx context.Context) string {
+	var userName, clusterName string
+	if u, ok := genericapirequest.UserFrom(ctx); ok {
+		userName = u.GetName()
+		if hash := u.GetExtra()[TokenHashExtraKey]; userName == user.Anonymous && len(hash) > 0 {
+			userName = "token:" + hash[0]
+		}
+	}
+	if cluster := genericapirequest.ClusterFrom(ctx); cluster != nil {
+		clusterName = cluster.Name.String()
+	}
+	return userName + "/" + clusterName
+}
+
+// limitFanOut takes a fan-out token per source of the request. It is a no-op
+// for requests that were not admitted by a TenantLimiter.
+func limitFanOut(ctx context.Context, sources int) error {
+	buckets, ok := ctx.Value(tenantBucketsKey{}).(*tenantBuckets)
+	if !ok || sources == 0 {
+		return nil
+	}
+
+	// a request fanning out to more workspaces than the burst would never be
+	// admitted otherwise, it has to wait for a full bucket instead
+	return take(ctx, buckets.fanOut, min(sources, buckets.fanOut.Burst()), buckets.clock.Now(), limitNameFanOut)
+}
+
+// take takes n tokens from limiter, or fails with a TooManyRequests error
+// telling the client when to retry.
+func take(ctx context.Context, limiter *rate.Limiter, n int, now time.Time, limit string) error {
+	reservation := limiter.ReserveN(now, n)
+	delay := reservation.DelayFrom(now)
+	if delay == 0 {
+		return nil
+	}
+	reservation.CancelAt(now)
+
+	var resource string
+	if info, ok := genericapirequest.RequestInfoFrom(ctx); ok {
+		resource = info.Resource
+	}
+	rateLimitedRequests.WithLabelValues(limit, resource).Inc()
+	return kerrors.NewTooManyRequests(
+		fmt.Sprintf("%s limit exceeded for this user and workspace, retry later", limit),
+		int(math.Ceil(delay.Seconds())),
+	)
+}
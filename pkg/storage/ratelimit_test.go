@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+	"github.com/kcp-dev/virtual-workspace-framework/pkg/forwardingregistry"
+	"github.com/platform-mesh/virtual-workspaces/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/internalversion"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/component-base/metrics/testutil"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+)
+
+func TestTenantLimiter(t *testing.T) {
+	t.Parallel()
+
+	tenantContext := func(userName string, cluster logicalcluster.Name) context.Context {
+		ctx := genericapirequest.WithUser(context.Background(), &user.DefaultInfo{Name: userName})
+		return genericapirequest.WithCluster(ctx, genericapirequest.Cluster{Name: cluster})
+	}
+
+	t.Run("limits requests per user and logical cluster", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := config.NewServiceConfig()
+		cfg.TenantRequestQPS = 1
+		cfg.TenantRequestBurst = 2
+		clk := clocktesting.NewFakeClock(time.Now())
+		lister := newTenantLimiter(cfg, clk).Lister(func(ctx context.Context, options *internalversion.ListOptions) (runtime.Object, error) {
+			return nil, nil
+		})
+
+		alice := tenantContext("alice", "team-a")
+		for range 2 {
+			_, err := lister(alice, &internalversion.ListOptions{})
+			require.NoError(t, err)
+		}
+
+		_, err := lister(alice, &internalversion.ListOptions{})
+		require.True(t, kerrors.IsTooManyRequests(err), "expected too many requests, got %v", err)
+		retryAfter, ok := kerrors.SuggestsClientDelay(err)
+		require.True(t, ok)
+		assert.Equal(t, 1, retryAfter)
+
+		// other tenants are not affected
+		_, err = lister(tenantContext("alice", "team-b"), &internalversion.ListOptions{})
+		require.NoError(t, err)
+		_, err = lister(tenantContext("bob", "team-a"), &internalversion.ListOptions{})
+		require.NoError(t, err)
+
+		clk.Step(time.Second)
+		_, err = lister(alice, &internalversion.ListOptions{})
+		require.NoError(t, err)
+	})
+
+	t.Run("tells unidentified callers apart by their token", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := config.NewServiceConfig()
+		cfg.TenantRequestQPS = 1
+		cfg.TenantRequestBurst = 1
+		lister := newTenantLimiter(cfg, clocktesting.NewFakeClock(time.Now())).Lister(func(ctx context.Context, options *internalversion.ListOptions) (runtime.Object, error) {
+			return nil, nil
+		})
+		unidentified := func(hash string) context.Context {
+			ctx := genericapirequest.WithUser(context.Background(), &user.DefaultInfo{
+				Name:  user.Anonymous,
+				Extra: map[string][]string{TokenHashExtraKey: {hash}},
+			})
+			return genericapirequest.WithCluster(ctx, genericapirequest.Cluster{Name: "team-a"})
+		}
+
+		_, err := lister(unidentified("a"), &internalversion.ListOptions{})
+		require.NoError(t, err)
+		_, err = lister(unidentified("b"), &internalversion.ListOptions{})
+		require.NoError(t, err)
+		_, err = lister(unidentified("a"), &internalversion.ListOptions{})
+		require.True(t, kerrors.IsTooManyRequests(err), "expected too many requests, got %v", err)
+	})
+
+	t.Run("limits the fan-out of admitted requests", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := config.NewServiceConfig()
+		cfg.TenantRequestQPS = 0
+		cfg.TenantFanOutQPS = 5
+		cfg.TenantFanOutBurst = 10
+		clk := clocktesting.NewFakeClock(time.Now())
+
+		var fanOutErrs []error
+		lister := newTenantLimiter(cfg, clk).Lister(func(ctx context.Context, options *internalversion.ListOptions) (runtime.Object, error) {
+			fanOutErrs = append(fanOutErrs, limitFanOut(ctx, 6))
+			return nil, nil
+		})
+
+		ctx := tenantContext("alice", "team-a")
+		for range 2 {
+			_, err := lister(ctx, &internalversion.ListOptions{})
+			require.NoError(t, err)
+		}
+
+		require.Len(t, fanOutErrs, 2)
+		require.NoError(t, fanOutErrs[0])
+		require.True(t, kerrors.IsTooManyRequests(fanOutErrs[1]), "expected too many requests, got %v", fanOutErrs[1])
+
+		// more sources than the burst are admitted with a full bucket
+		fresh := newTenantLimiter(cfg, clk).bucketsFor(ctx)
+		require.NoError(t, limitFanOut(context.WithValue(ctx, tenantBucketsKey{}, fresh), 50))
+	})
+
+	t.Run("limits watches and counts rejections by resource", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := config.NewServiceConfig()
+		cfg.TenantRequestQPS = 1
+		cfg.TenantRequestBurst = 1
+		clk := clocktesting.NewFakeClock(time.Now())
+
+		store := &forwardingregistry.StoreFuncs{
+			ListerFunc: func(ctx context.Context, options *internalversion.ListOptions) (runtime.Object, error) {
+				return nil, nil
+			},
+			WatcherFunc: func(ctx context.Context, options *internalversion.ListOptions) (watch.Interface, error) {
+				return watch.NewEmptyWatch(), nil
+			},
+		}
+		newTenantLimiter(cfg, clk).Wrapper().Decorate(schema.GroupResource{Resource: "ratelimitedwatches"}, store)
+
+		ctx := genericapirequest.WithRequestInfo(tenantContext("alice", "team-a"), &genericapirequest.RequestInfo{Resource: "ratelimitedwatches"})
+		rejected := rateLimitedRequests.WithLabelValues(limitNameRequests, "ratelimitedwatches")
+
+		_, err := store.WatcherFunc(ctx, &internalversion.ListOptions{})
+		require.NoError(t, err)
+		_, err = store.WatcherFunc(ctx, &internalversion.ListOptions{})
+		require.True(t, kerrors.IsTooManyRequests(err), "expected too many requests, got %v", err)
+
+		count, err := testutil.GetCounterMetricValue(rejected)
+		require.NoError(t, err)
+		assert.Equal(t, float64(1), count)
+	})
+
+	t.Run("requests that were not admitted are not limited", func(t *testing.T) {
+		t.Parallel()
+
+		assert.NoError(t, limitFanOut(context.Background(), 1000))
+	})
+}
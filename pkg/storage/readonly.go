@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/kcp-dev/virtual-workspace-framework/pkg/dynamic/apiserver"
+	registry "github.com/kcp-dev/virtual-workspace-framework/pkg/forwardingregistry"
+	structuralschema "k8s.io/apiextensions-apiserver/pkg/apiserver/schema"
+	"k8s.io/apiextensions-apiserver/pkg/apiserver/validation"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/internalversion"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/apiserver/pkg/registry/rest"
+	"sigs.k8s.io/structured-merge-diff/v6/fieldpath"
+)
+
+// readOnlyHandlersFunc returns the get and list handlers of a read-only
+// resource for the resource, kind and list kind it is served as.
+type readOnlyHandlersFunc func(resource schema.GroupVersionResource, kind, listKind schema.GroupVersionKind) (registry.GetterFunc, registry.ListerFunc)
+
+// createReadOnlyStorageProviderFunc returns a storage provider for a read-only
+// resource that is not forwarded to kcp but answered by the handlers alone.
+// Watches are not supported.
+func createReadOnlyStorageProviderFunc(handlers readOnlyHandlersFunc) func(ctx context.Context) (apiserver.RestProviderFunc, error) {
+	return func(ctx context.Context) (apiserver.RestProviderFunc, error) {
+
+		return func(resource schema.GroupVersionResource, kind, listKind schema.GroupVersionKind, typer runtime.ObjectTyper, tableConvertor rest.TableConvertor, namespaceScoped bool, schemaValidator validation.SchemaValidator, subresourcesSchemaValidator map[string]validation.SchemaValidator, structuralSchema *structuralschema.Structural) (mainStorage rest.Storage, subresourceStorages map[string]rest.Storage) {
+			getter, lister := handlers(resource, kind, listKind)
+
+			// only expose GET/LIST (list needs watch, which is not supported)
+			return &struct {
+				registry.FactoryFunc
+				registry.ListFactoryFunc
+				registry.DestroyerFunc
+
+				registry.GetterFunc
+				registry.ListerFunc
+				registry.WatcherFunc
+
+				registry.TableConvertorFunc
+				registry.CategoriesProviderFunc
+				registry.ResetFieldsStrategyFunc
+			}{
+				FactoryFunc: func() runtime.Object {
+					us := &unstructured.Unstructured{}
+					us.SetGroupVersionKind(kind)
+					return us
+				},
+				ListFactoryFunc: func() runtime.Object {
+					ul := &unstructured.UnstructuredList{}
+					ul.SetGroupVersionKind(listKind)
+					return ul
+				},
+				DestroyerFunc: func() {},
+
+				GetterFunc: getter,
+				ListerFunc: lister,
+				WatcherFunc: func(ctx context.Context, options *internalversion.ListOptions) (watch.Interface, error) {
+					return nil, kerrors.NewMethodNotSupported(resource.GroupResource(), "watch")
+				},
+
+				TableConvertorFunc:     tableConvertor.ConvertToTable,
+				CategoriesProviderFunc: func() []string { return nil },
+				ResetFieldsStrategyFunc: func() map[fieldpath.APIVersion]*fieldpath.Set {
+					return nil
+				},
+			}, nil
+		}, nil
+
+	}
+}
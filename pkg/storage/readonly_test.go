@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/internalversion"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apiserver/pkg/registry/rest"
+)
+
+func TestCreateCatalogStorageProviderFunc(t *testing.T) {
+	t.Parallel()
+
+	lister := func(ctx context.Context, options *internalversion.ListOptions) (runtime.Object, error) {
+		return &unstructured.UnstructuredList{Items: []unstructured.Unstructured{
+			newCC("free", map[string]string{"plan": "free"}, false),
+			newCC("premium", map[string]string{"plan": "premium"}, false),
+		}}, nil
+	}
+
+	provider, err := CreateCatalogStorageProviderFunc(lister)(context.Background())
+	require.NoError(t, err)
+	kind := ContentConfigurationGVR.GroupVersion().WithKind("ContentConfiguration")
+	mainStorage, _ := provider(ContentConfigurationGVR, kind, contentConfigurationListGVK, runtime.NewScheme(), rest.NewDefaultTableConvertor(ContentConfigurationGVR.GroupResource()), false, nil, nil, nil)
+
+	obj, err := mainStorage.(rest.Getter).Get(context.Background(), "premium", &metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "premium", obj.(*unstructured.Unstructured).GetName())
+
+	_, err = mainStorage.(rest.Getter).Get(context.Background(), "missing", &metav1.GetOptions{})
+	assert.True(t, kerrors.IsNotFound(err), "unexpected error: %v", err)
+
+	list, err := mainStorage.(rest.Lister).List(context.Background(), &internalversion.ListOptions{LabelSelector: labels.SelectorFromSet(labels.Set{"plan": "free"})})
+	require.NoError(t, err)
+	ul := list.(*unstructured.UnstructuredList)
+	require.Len(t, ul.Items, 1)
+	assert.Equal(t, "free", ul.Items[0].GetName())
+	assert.Equal(t, contentConfigurationListGVK, ul.GroupVersionKind())
+
+	_, err = mainStorage.(rest.Watcher).Watch(context.Background(), &internalversion.ListOptions{})
+	assert.True(t, kerrors.IsMethodNotSupported(err), "unexpected error: %v", err)
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kcp-dev/virtual-workspace-framework/pkg/dynamic/apiserver"
+	registry "github.com/kcp-dev/virtual-workspace-framework/pkg/forwardingregistry"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/internalversion"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// createSingletonStorageProviderFunc returns a storage provider for a read-only
+// resource with a single object of the given name, built by build on every
+// request. Only the label selector of list requests is passed on to build.
+func createSingletonStorageProviderFunc(name string, build func(ctx context.Context, options *internalversion.ListOptions) (any, error)) func(ctx context.Context) (apiserver.RestProviderFunc, error) {
+	return createReadOnlyStorageProviderFunc(func(resource schema.GroupVersionResource, kind, listKind schema.GroupVersionKind) (registry.GetterFunc, registry.ListerFunc) {
+		compile := func(ctx context.Context, options *internalversion.ListOptions) (*unstructured.Unstructured, error) {
+			ccOpts := &internalversion.ListOptions{}
+			if options != nil {
+				ccOpts.LabelSelector = options.LabelSelector
+			}
+
+			obj, err := build(ctx, ccOpts)
+			if err != nil {
+				return nil, err
+			}
+
+			raw, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert %s to unstructured: %w", resource.GroupResource(), err)
+			}
+
+			us := &unstructured.Unstructured{Object: raw}
+			us.SetGroupVersionKind(kind)
+			return us, nil
+		}
+
+		getter := func(ctx context.Context, objName string, options *metav1.GetOptions) (runtime.Object, error) {
+			if objName != name {
+				return nil, kerrors.NewNotFound(resource.GroupResource(), objName)
+			}
+			return compile(ctx, nil)
+		}
+		lister := func(ctx context.Context, options *internalversion.ListOptions) (runtime.Object, error) {
+			obj, err := compile(ctx, options)
+			if err != nil {
+				return nil, err
+			}
+
+			ul := &unstructured.UnstructuredList{Items: []unstructured.Unstructured{*obj}}
+			ul.SetGroupVersionKind(listKind)
+			return ul, nil
+		}
+
+		return getter, lister
+	})
+}
@@ -0,0 +1,163 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+	"github.com/kcp-dev/virtual-workspace-framework/pkg/forwardingregistry"
+	"golang.org/x/sync/errgroup"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/internalversion"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/apiserver/pkg/warning"
+)
+
+// contentSource is a workspace contentconfigurations are collected from in
+// addition to the requested workspace, e.g. an APIExport or the provider workspace.
+type contentSource struct {
+	// kind and name identify the source in logs, e.g. "apiexport" and the export name.
+	kind string
+	name string
+
+	cluster  logicalcluster.Name
+	selector labels.Selector
+
+	// diagnosticSelector replaces selector while collecting ContentDiagnostics,
+	// so mismatches can be reported. It must only select contentconfigurations
+	// the caller may learn about; sources without one are listed with selector.
+	diagnosticSelector labels.Selector
+
+	// optional sources do not fail the request: NotFound is treated as an empty
+	// result and any other error skips the source with a warning.
+	optional bool
+
+	// inactiveReason is set for sources that are only included for diagnostics;
+	// their contentconfigurations are annotated as inactive.
+	inactiveReason string
+
+	// excludeReason is set for sources that are only listed for ContentDiagnostics;
+	// all their contentconfigurations are excluded with that reason.
+	excludeReason string
+
+	// mismatchReasons maps the label keys of selector to the reason reported in
+	// ContentDiagnostics for contentconfigurations not matching them.
+	mismatchReasons map[string]string
+}
+
+const (
+	skipReasonIncompleteBinding = "incomplete_binding"
+	skipReasonListFailed        = "list_failed"
+	skipReasonInactiveBinding   = "inactive_binding"
+	skipReasonUnresolvable      = "unresolvable_workspace"
+)
+
+// skipContentSource records that a source was left out of the response. The
+// caller is informed via an apiserver warning, so a single broken source does
+// not go unnoticed while the remaining content is still served.
+func skipContentSource(ctx context.Context, kind, name, reason string, err error) {
+	klog.ErrorS(err, "skipping contentconfiguration source", "source", kind, "name", name, "reason", reason)
+	skippedContentSources.WithLabelValues(kind, reason).Inc()
+
+	diagnosticReason := reason
+	if err != nil {
+		diagnosticReason = fmt.Sprintf("%s: %v", reason, err)
+	}
+	diagnosticsFrom(ctx).exclude(contentSource{kind: kind, name: name}, "", diagnosticReason)
+
+	warning.AddWarning(ctx, "", fmt.Sprintf("contentconfigurations from %s %q were skipped: %s", kind, name, reason))
+}
+
+// listContentSources lists the contentconfigurations of all sources with at most
+// concurrency requests in flight, each bounded by timeout. The results are
+// returned in the order of the sources, independent of completion order.
+func listContentSources(
+	ctx context.Context,
+	lister forwardingregistry.ListerFunc,
+	options *internalversion.ListOptions,
+	sources []contentSource,
+	concurrency int,
+	timeout time.Duration,
+) ([][]unstructured.Unstructured, error) {
+	results := make([][]unstructured.Unstructured, len(sources))
+
+	g, gctx := errgroup.WithContext(ctx)
+	if concurrency > 0 {
+		g.SetLimit(concurrency)
+	}
+
+	for i, source := range sources {
+		g.Go(func() error {
+			sourceCtx := gctx
+			if timeout > 0 {
+				var cancel context.CancelFunc
+				sourceCtx, cancel = context.WithTimeout(gctx, timeout)
+				defer cancel()
+			}
+
+			sourceCtx = genericapirequest.WithCluster(sourceCtx, genericapirequest.Cluster{
+				Name: source.cluster,
+			})
+
+			// Diagnostics tell why contentconfigurations are excluded, so the
+			// selector is applied after listing where the source allows it.
+			sourceOpts := options.DeepCopy()
+			sourceOpts.LabelSelector = source.selector
+			if diagnosticsFrom(ctx) != nil && source.diagnosticSelector != nil {
+				sourceOpts.LabelSelector = source.diagnosticSelector
+			}
+
+			ccs, err := lister.List(sourceCtx, sourceOpts)
+			if err != nil && gctx.Err() != nil {
+				// another source failed or the request is gone, this source
+				// was only canceled and is not worth reporting
+				return gctx.Err()
+			}
+			if source.optional && kerrors.IsNotFound(err) {
+				return nil
+			}
+			if source.optional && err != nil {
+				skipContentSource(ctx, source.kind, source.name, skipReasonListFailed, err)
+				return nil
+			}
+			if err != nil {
+				klog.ErrorS(err, "failed to list contentconfigurations", "source", source.kind, "name", source.name, "workspace", source.cluster)
+				return err
+			}
+
+			items := collectSourceItems(ctx, source, ccs.(*unstructured.UnstructuredList))
+			if source.excludeReason != "" {
+				for _, item := range items {
+					diagnosticsFrom(ctx).exclude(source, item.GetName(), source.excludeReason)
+				}
+				return nil
+			}
+			if source.inactiveReason != "" {
+				for j := range items {
+					annotations := items[j].GetAnnotations()
+					if annotations == nil {
+						annotations = map[string]string{}
+					}
+					annotations[InactiveAnnotation] = "true"
+					annotations[InactiveReasonAnnotation] = source.inactiveReason
+					items[j].SetAnnotations(annotations)
+				}
+			}
+
+			results[i] = items
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
@@ -0,0 +1,148 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/internalversion"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+)
+
+func TestListContentSources_KeepsSourceOrder(t *testing.T) {
+	t.Parallel()
+
+	var inFlight, maxInFlight atomic.Int32
+	delays := map[logicalcluster.Name]time.Duration{
+		"ws-a": 30 * time.Millisecond,
+		"ws-b": 20 * time.Millisecond,
+		"ws-c": 10 * time.Millisecond,
+		"ws-d": 0,
+	}
+
+	lister := func(ctx context.Context, _ *internalversion.ListOptions) (runtime.Object, error) {
+		current := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			observed := maxInFlight.Load()
+			if current <= observed || maxInFlight.CompareAndSwap(observed, current) {
+				break
+			}
+		}
+
+		cluster := genericapirequest.ClusterFrom(ctx)
+		time.Sleep(delays[cluster.Name])
+
+		return &unstructured.UnstructuredList{Items: []unstructured.Unstructured{
+			newCC(cluster.Name.String(), nil, true),
+		}}, nil
+	}
+
+	var sources []contentSource
+	for _, name := range []string{"ws-a", "ws-b", "ws-c", "ws-d"} {
+		sources = append(sources, contentSource{kind: "apiexport", name: name, cluster: logicalcluster.Name(name), selector: labels.Everything()})
+	}
+
+	results, err := listContentSources(context.Background(), lister, &internalversion.ListOptions{}, sources, 2, time.Second)
+	require.NoError(t, err)
+
+	var gotNames []string
+	for _, items := range results {
+		for _, item := range items {
+			gotNames = append(gotNames, item.GetName())
+		}
+	}
+
+	assert.Equal(t, []string{"ws-a", "ws-b", "ws-c", "ws-d"}, gotNames)
+	assert.LessOrEqual(t, maxInFlight.Load(), int32(2))
+}
+
+func TestListContentSources_Errors(t *testing.T) {
+	t.Parallel()
+
+	notFound := func(_ context.Context, _ *internalversion.ListOptions) (runtime.Object, error) {
+		return nil, kerrors.NewNotFound(schema.GroupResource{Group: "ui.platform-mesh.io", Resource: "contentconfigurations"}, "")
+	}
+	slow := func(ctx context.Context, _ *internalversion.ListOptions) (runtime.Object, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	tests := []struct {
+		name      string
+		lister    func(context.Context, *internalversion.ListOptions) (runtime.Object, error)
+		source    contentSource
+		expectErr bool
+	}{
+		{
+			name:   "not found is ignored for optional sources",
+			lister: notFound,
+			source: contentSource{kind: "apiexport", cluster: "ws", optional: true},
+		},
+		{
+			name:      "not found fails required sources",
+			lister:    notFound,
+			source:    contentSource{kind: "provider", cluster: "ws"},
+			expectErr: true,
+		},
+		{
+			name:   "failing optional source is skipped",
+			lister: slow,
+			source: contentSource{kind: "apiexport", cluster: "ws", optional: true},
+		},
+		{
+			name:      "slow source times out",
+			lister:    slow,
+			source:    contentSource{kind: "apiexport", cluster: "ws"},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := listContentSources(context.Background(), tt.lister, &internalversion.ListOptions{}, []contentSource{tt.source}, 1, 10*time.Millisecond)
+			if tt.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestListContentSources_CanceledSourcesAreNotSkipped(t *testing.T) {
+	t.Parallel()
+
+	lister := func(ctx context.Context, _ *internalversion.ListOptions) (runtime.Object, error) {
+		if genericapirequest.ClusterFrom(ctx).Name == "failing" {
+			return nil, kerrors.NewInternalError(errors.New("boom"))
+		}
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	sources := []contentSource{
+		{kind: "apiexport", name: "waiting-a", cluster: "waiting-a", optional: true},
+		{kind: "apiexport", name: "waiting-b", cluster: "waiting-b", optional: true},
+		{kind: "provider", name: "failing", cluster: "failing"},
+	}
+
+	ctx, d := withDiagnostics(context.Background())
+	_, err := listContentSources(ctx, lister, &internalversion.ListOptions{}, sources, 0, time.Minute)
+	require.True(t, kerrors.IsInternalError(err), "expected the error of the failing source, got %v", err)
+	assert.Empty(t, d.excluded)
+}
@@ -17,7 +17,21 @@ import (
 	"k8s.io/apiserver/pkg/registry/rest"
 )
 
-func CreateStorageProviderFunc(clusterClient dynamic.ClusterInterface, filters ...registry.StorageWrapper) func(ctx context.Context) (apiserver.RestProviderFunc, error) {
+// CreateStorageProviderFunc returns a read-only storage forwarding to the
+// requested workspace with clusterClient. If readClient is set, gets and
+// watches are forwarded with it instead, e.g. as the caller with
+// ImpersonatingClusterClient; lists are left to the filters.
+func CreateStorageProviderFunc(clusterClient dynamic.ClusterInterface, readClient registry.DynamicClusterClientFunc, filters ...registry.StorageWrapper) func(ctx context.Context) (apiserver.RestProviderFunc, error) {
+	return createStorageProviderFunc(clusterClient, readClient, nil, filters...)
+}
+
+// CreateWritableStorageProviderFunc is CreateStorageProviderFunc additionally
+// serving create, update, patch and delete through writer.
+func CreateWritableStorageProviderFunc(clusterClient dynamic.ClusterInterface, readClient registry.DynamicClusterClientFunc, writer *Writer, filters ...registry.StorageWrapper) func(ctx context.Context) (apiserver.RestProviderFunc, error) {
+	return createStorageProviderFunc(clusterClient, readClient, writer, filters...)
+}
+
+func createStorageProviderFunc(clusterClient dynamic.ClusterInterface, readClient registry.DynamicClusterClientFunc, writer *Writer, filters ...registry.StorageWrapper) func(ctx context.Context) (apiserver.RestProviderFunc, error) {
 	return func(ctx context.Context) (apiserver.RestProviderFunc, error) {
 
 		return func(resource schema.GroupVersionResource, kind, listKind schema.GroupVersionKind, typer runtime.ObjectTyper, tableConvertor rest.TableConvertor, namespaceScoped bool, schemaValidator validation.SchemaValidator, subresourcesSchemaValidator map[string]validation.SchemaValidator, structuralSchema *structuralschema.Structural) (mainStorage rest.Storage, subresourceStorages map[string]rest.Storage) {
@@ -42,6 +56,30 @@ func CreateStorageProviderFunc(clusterClient dynamic.ClusterInterface, filters .
 
 			wrappers := registry.StorageWrappers{}
 
+			// the filters read export and provider workspaces with the lister of
+			// clusterClient, only gets and watches of the requested workspace can
+			// be forwarded as the caller. This goes first, so the filters wrap it.
+			if readClient != nil {
+				readStorage, _ := registry.NewStorage(
+					ctx,
+					resource,
+					"",
+					kind,
+					listKind,
+					strategy,
+					nil,
+					tableConvertor,
+					nil,
+					readClient,
+					nil,
+					nil,
+				)
+				wrappers = append(wrappers, registry.StorageWrapperFunc(func(_ schema.GroupResource, storage *registry.StoreFuncs) {
+					storage.GetterFunc = readStorage.GetterFunc
+					storage.WatcherFunc = readStorage.WatcherFunc
+				}))
+			}
+
 			for _, filter := range filters {
 				wrappers = append(wrappers, filter)
 			}
@@ -88,6 +126,59 @@ func CreateStorageProviderFunc(clusterClient dynamic.ClusterInterface, filters .
 				}
 			}
 
+			if writer != nil {
+				// writes bypass the filters, they only ever target the requested workspace
+				writeStorage, _ := registry.NewStorage(
+					ctx,
+					resource,
+					"",
+					kind,
+					listKind,
+					strategy,
+					nil,
+					tableConvertor,
+					nil,
+					writer.ClientFunc,
+					nil,
+					nil,
+				)
+				writer.decorate(resource.GroupResource(), kind.GroupKind(), strategy, writeStorage)
+
+				return &struct {
+					registry.FactoryFunc
+					registry.ListFactoryFunc
+					registry.DestroyerFunc
+
+					registry.GetterFunc
+					registry.ListerFunc
+					registry.WatcherFunc
+
+					registry.CreaterFunc
+					registry.UpdaterFunc
+					registry.GracefulDeleterFunc
+
+					registry.TableConvertorFunc
+					registry.CategoriesProviderFunc
+					registry.ResetFieldsStrategyFunc
+				}{
+					FactoryFunc:     storage.FactoryFunc,
+					ListFactoryFunc: storage.ListFactoryFunc,
+					DestroyerFunc:   storage.DestroyerFunc,
+
+					GetterFunc:  storage.GetterFunc,
+					ListerFunc:  storage.ListerFunc,
+					WatcherFunc: storage.WatcherFunc,
+
+					CreaterFunc:         writeStorage.CreaterFunc,
+					UpdaterFunc:         writeStorage.UpdaterFunc,
+					GracefulDeleterFunc: writeStorage.GracefulDeleterFunc,
+
+					TableConvertorFunc:      storage.TableConvertorFunc,
+					CategoriesProviderFunc:  storage.CategoriesProviderFunc,
+					ResetFieldsStrategyFunc: storage.ResetFieldsStrategyFunc,
+				}, subresourceStorages
+			}
+
 			// only expose GET/LIST (list needs watch)
 			return &struct {
 				registry.FactoryFunc
@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	kcpdynamic "github.com/kcp-dev/client-go/dynamic"
+	"github.com/kcp-dev/logicalcluster/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/registry/rest"
+	"k8s.io/client-go/dynamic"
+
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+)
+
+// forbiddenDynamicClusterClient denies every get, like kcp does for a caller
+// without access to the workspace.
+type forbiddenDynamicClusterClient struct {
+	kcpdynamic.ClusterInterface
+}
+
+func (f *forbiddenDynamicClusterClient) Cluster(logicalcluster.Path) dynamic.Interface {
+	return &forbiddenDynamicInterface{}
+}
+
+type forbiddenDynamicInterface struct {
+	dynamic.Interface
+}
+
+func (f *forbiddenDynamicInterface) Resource(gvr schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	return &forbiddenResource{resource: gvr.GroupResource()}
+}
+
+type forbiddenResource struct {
+	dynamic.NamespaceableResourceInterface
+
+	resource schema.GroupResource
+}
+
+func (f *forbiddenResource) Get(_ context.Context, name string, _ metav1.GetOptions, _ ...string) (*unstructured.Unstructured, error) {
+	return nil, kerrors.NewForbidden(f.resource, name, fmt.Errorf("no access to the workspace"))
+}
+
+func TestCreateStorageProviderFunc_GetsAsCaller(t *testing.T) {
+	t.Parallel()
+
+	serviceClient := &fakeDynamicClusterClient{objects: map[logicalcluster.Path]map[string][]unstructured.Unstructured{
+		logicalcluster.NewPath("team-a"): {ContentConfigurationGVR.Resource: {newCC("local", nil, true)}},
+	}}
+	readClient := func(ctx context.Context) (kcpdynamic.ClusterInterface, error) {
+		if u, ok := genericapirequest.UserFrom(ctx); ok && u.GetName() == "alice" {
+			return serviceClient, nil
+		}
+		return &forbiddenDynamicClusterClient{}, nil
+	}
+
+	tests := []struct {
+		name        string
+		readClient  func(ctx context.Context) (kcpdynamic.ClusterInterface, error)
+		user        string
+		expectedErr func(error) bool
+	}{
+		{name: "service identity without impersonation", user: "bob"},
+		{name: "caller with access to the workspace", readClient: readClient, user: "alice"},
+		{name: "caller without access to the workspace", readClient: readClient, user: "bob", expectedErr: kerrors.IsForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			provider, err := createStorageProviderFunc(serviceClient, tt.readClient, nil)(context.Background())
+			require.NoError(t, err)
+			kind := ContentConfigurationGVR.GroupVersion().WithKind("ContentConfiguration")
+			mainStorage, _ := provider(ContentConfigurationGVR, kind, contentConfigurationListGVK, runtime.NewScheme(), rest.NewDefaultTableConvertor(ContentConfigurationGVR.GroupResource()), false, nil, nil, nil)
+
+			ctx := genericapirequest.WithCluster(context.Background(), genericapirequest.Cluster{Name: "team-a"})
+			ctx = genericapirequest.WithUser(ctx, &user.DefaultInfo{Name: tt.user})
+			obj, err := mainStorage.(rest.Getter).Get(ctx, "local", &metav1.GetOptions{})
+			if tt.expectedErr != nil {
+				require.Error(t, err)
+				assert.True(t, tt.expectedErr(err), "unexpected error: %v", err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "local", obj.(*unstructured.Unstructured).GetName())
+		})
+	}
+}
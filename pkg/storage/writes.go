@@ -0,0 +1,228 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/kcp-dev/client-go/dynamic"
+	"github.com/kcp-dev/logicalcluster/v3"
+	"github.com/kcp-dev/virtual-workspace-framework/pkg/forwardingregistry"
+	"github.com/platform-mesh/virtual-workspaces/pkg/config"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/apiserver/pkg/registry/rest"
+
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+)
+
+// Writer forwards create, update, patch and delete of contentconfigurations to
+// the requested workspace.
+type Writer struct {
+	// ClientFunc returns the client writes are sent with. It is expected to act
+	// as the caller, e.g. ImpersonatingClusterClient.
+	ClientFunc forwardingregistry.DynamicClusterClientFunc
+
+	// Projected reports whether a name that does not exist in the requested
+	// workspace is projected into it from an export or provider workspace, e.g.
+	// ProjectedContentConfiguration. Such names cannot be written.
+	Projected func(ctx context.Context, name string) (bool, error)
+
+	// ContentForLabel marks contentconfigurations projected into the workspace
+	// by an APIBinding. Objects carrying it, before or after the write, cannot
+	// be written.
+	ContentForLabel string
+
+	// Limiter limits the creates, updates and deletes per tenant, if set.
+	Limiter *TenantLimiter
+}
+
+// schemaValidator validates objects against the served schema, as implemented
+// by the custom resource strategy.
+type schemaValidator interface {
+	Validate(ctx context.Context, obj runtime.Object) field.ErrorList
+	ValidateUpdate(ctx context.Context, obj, old runtime.Object) field.ErrorList
+}
+
+// decorate replaces the write funcs of store, which forwards with ClientFunc,
+// by ones validating against validator and rejecting projected objects.
+// Writes are limited by Limiter before anything is read.
+func (w *Writer) decorate(resource schema.GroupResource, kind schema.GroupKind, validator schemaValidator, store *forwardingregistry.StoreFuncs) {
+	get := store.GetterFunc
+	create := store.CreaterFunc
+	update := store.UpdaterFunc
+	gracefulDelete := store.GracefulDeleterFunc
+
+	projected := func(name string) error {
+		return kerrors.NewForbidden(resource, name, fmt.Errorf("contentconfiguration is projected into the workspace and cannot be modified"))
+	}
+
+	rejectContentFor := func(obj runtime.Object) error {
+		if w.ContentForLabel == "" {
+			return nil
+		}
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			return err
+		}
+		if _, ok := accessor.GetLabels()[w.ContentForLabel]; ok {
+			return projected(accessor.GetName())
+		}
+		return nil
+	}
+
+	rejectProjected := func(ctx context.Context, name string) error {
+		if name == "" {
+			return nil
+		}
+
+		existing, err := get(ctx, name, &metav1.GetOptions{})
+		if err == nil {
+			return rejectContentFor(existing)
+		}
+		if !kerrors.IsNotFound(err) {
+			return err
+		}
+
+		isProjected, err := w.Projected(ctx, name)
+		if err != nil {
+			return err
+		}
+		if isProjected {
+			return projected(name)
+		}
+
+		return nil
+	}
+
+	validateCreate := func(createValidation rest.ValidateObjectFunc) rest.ValidateObjectFunc {
+		return func(ctx context.Context, obj runtime.Object) error {
+			if err := rejectContentFor(obj); err != nil {
+				return err
+			}
+			if errs := validator.Validate(ctx, obj); len(errs) > 0 {
+				return kerrors.NewInvalid(kind, nameOf(obj), errs)
+			}
+			if createValidation == nil {
+				return nil
+			}
+			return createValidation(ctx, obj)
+		}
+	}
+
+	validateUpdate := func(updateValidation rest.ValidateObjectUpdateFunc) rest.ValidateObjectUpdateFunc {
+		return func(ctx context.Context, obj, old runtime.Object) error {
+			if err := rejectContentFor(obj); err != nil {
+				return err
+			}
+			if errs := validator.ValidateUpdate(ctx, obj, old); len(errs) > 0 {
+				return kerrors.NewInvalid(kind, nameOf(obj), errs)
+			}
+			if updateValidation == nil {
+				return nil
+			}
+			return updateValidation(ctx, obj, old)
+		}
+	}
+
+	store.CreaterFunc = func(ctx context.Context, obj runtime.Object, createValidation rest.ValidateObjectFunc, options *metav1.CreateOptions) (runtime.Object, error) {
+		if err := rejectProjected(ctx, nameOf(obj)); err != nil {
+			return nil, err
+		}
+		return create(ctx, obj, validateCreate(createValidation), options)
+	}
+	store.UpdaterFunc = func(ctx context.Context, name string, objInfo rest.UpdatedObjectInfo, createValidation rest.ValidateObjectFunc, updateValidation rest.ValidateObjectUpdateFunc, forceAllowCreate bool, options *metav1.UpdateOptions) (runtime.Object, bool, error) {
+		if err := rejectProjected(ctx, name); err != nil {
+			return nil, false, err
+		}
+		return update(ctx, name, objInfo, validateCreate(createValidation), validateUpdate(updateValidation), forceAllowCreate, options)
+	}
+	store.GracefulDeleterFunc = func(ctx context.Context, name string, deleteValidation rest.ValidateObjectFunc, options *metav1.DeleteOptions) (runtime.Object, bool, error) {
+		if err := rejectProjected(ctx, name); err != nil {
+			return nil, false, err
+		}
+		return gracefulDelete(ctx, name, deleteValidation, options)
+	}
+
+	if w.Limiter != nil {
+		w.Limiter.WriteWrapper().Decorate(resource, store)
+	}
+}
+
+// ProjectedContentConfiguration returns a check whether a contentconfiguration
+// of the given name is served to the requested workspace from a bound export or
+// a provider workspace. Unlike a list of the served contentconfigurations, it
+// reads the APIBindings of the workspace and looks the name up in cache, or
+// gets it from workspaces that are not cached.
+func ProjectedContentConfiguration(client dynamic.ClusterInterface, cfg config.ServiceConfig, providers []ProviderWorkspace, cache ContentConfigurationCache) func(ctx context.Context, name string) (bool, error) {
+	servedBy := func(ctx context.Context, cluster logicalcluster.Name, selector labels.Selector, name string) (bool, error) {
+		if cache != nil {
+			items, ok, err := cache.List(ctx, cluster, selector)
+			if err != nil {
+				return false, err
+			}
+			if ok {
+				return slices.ContainsFunc(items, func(item unstructured.Unstructured) bool {
+					return item.GetName() == name
+				}), nil
+			}
+		}
+
+		item, err := client.Cluster(cluster.Path()).Resource(ContentConfigurationGVR).Get(ctx, name, metav1.GetOptions{})
+		if kerrors.IsNotFound(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return selector.Matches(labels.Set(item.GetLabels())), nil
+	}
+
+	return func(ctx context.Context, name string) (bool, error) {
+		for _, provider := range providers {
+			ok, err := servedBy(ctx, provider.Cluster, labels.Everything(), name)
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+
+		cluster := genericapirequest.ClusterFrom(ctx)
+		if cluster == nil {
+			return false, kerrors.NewBadRequest("cluster not found in context")
+		}
+		apiBindings, err := client.Cluster(cluster.Name.Path()).Resource(apiBindingGVR).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return false, err
+		}
+		for _, binding := range apiBindings.Items {
+			exportName, _, _ := unstructured.NestedString(binding.Object, "spec", "reference", "export", "name")
+			exportCluster, _, _ := unstructured.NestedString(binding.Object, "status", "apiExportClusterName")
+			if exportName == "" || exportCluster == "" {
+				continue
+			}
+
+			selector := labels.SelectorFromValidatedSet(map[string]string{cfg.ContentForLabel: exportName})
+			ok, err := servedBy(ctx, logicalcluster.Name(exportCluster), selector, name)
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+
+		return false, nil
+	}
+}
+
+func nameOf(obj runtime.Object) string {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return ""
+	}
+	return accessor.GetName()
+}
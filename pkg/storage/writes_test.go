@@ -0,0 +1,354 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+	"github.com/kcp-dev/virtual-workspace-framework/pkg/forwardingregistry"
+	"github.com/platform-mesh/virtual-workspaces/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/registry/rest"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+)
+
+type fakeSchemaValidator struct{}
+
+func (fakeSchemaValidator) Validate(_ context.Context, obj runtime.Object) field.ErrorList {
+	if _, found, _ := unstructured.NestedString(obj.(*unstructured.Unstructured).Object, "spec", "invalid"); found {
+		return field.ErrorList{field.Forbidden(field.NewPath("spec", "invalid"), "not allowed")}
+	}
+	return nil
+}
+
+func (v fakeSchemaValidator) ValidateUpdate(ctx context.Context, obj, _ runtime.Object) field.ErrorList {
+	return v.Validate(ctx, obj)
+}
+
+const testContentForLabel = "ui.platform-mesh.io/content-for"
+
+func TestWriter(t *testing.T) {
+	t.Parallel()
+
+	contentFor := map[string]string{testContentForLabel: "openmcp.cloud"}
+	invalid := newCC("local", nil, false)
+	_ = unstructured.SetNestedField(invalid.Object, "yes", "spec", "invalid")
+
+	tests := []struct {
+		name          string
+		write         func(ctx context.Context, store *forwardingregistry.StoreFuncs) error
+		expectedErr   func(error) bool
+		expectWritten bool
+	}{
+		{
+			name: "create in the workspace",
+			write: func(ctx context.Context, store *forwardingregistry.StoreFuncs) error {
+				obj := newCC("new", nil, false)
+				_, err := store.Create(ctx, &obj, nil, &metav1.CreateOptions{})
+				return err
+			},
+			expectWritten: true,
+		},
+		{
+			name: "create shadowing a projected contentconfiguration",
+			write: func(ctx context.Context, store *forwardingregistry.StoreFuncs) error {
+				obj := newCC("projected", nil, false)
+				_, err := store.Create(ctx, &obj, nil, &metav1.CreateOptions{})
+				return err
+			},
+			expectedErr: kerrors.IsForbidden,
+		},
+		{
+			name: "create violating the schema",
+			write: func(ctx context.Context, store *forwardingregistry.StoreFuncs) error {
+				obj := invalid.DeepCopy()
+				obj.SetName("new")
+				_, err := store.Create(ctx, obj, nil, &metav1.CreateOptions{})
+				return err
+			},
+			expectedErr: kerrors.IsInvalid,
+		},
+		{
+			name: "update in the workspace",
+			write: func(ctx context.Context, store *forwardingregistry.StoreFuncs) error {
+				obj := newCC("local", nil, false)
+				_, _, err := store.Update(ctx, "local", rest.DefaultUpdatedObjectInfo(&obj), nil, nil, false, &metav1.UpdateOptions{})
+				return err
+			},
+			expectWritten: true,
+		},
+		{
+			name: "update violating the schema",
+			write: func(ctx context.Context, store *forwardingregistry.StoreFuncs) error {
+				_, _, err := store.Update(ctx, "local", rest.DefaultUpdatedObjectInfo(invalid.DeepCopy()), nil, nil, false, &metav1.UpdateOptions{})
+				return err
+			},
+			expectedErr: kerrors.IsInvalid,
+		},
+		{
+			name: "update of a projected contentconfiguration",
+			write: func(ctx context.Context, store *forwardingregistry.StoreFuncs) error {
+				obj := newCC("projected", nil, false)
+				_, _, err := store.Update(ctx, "projected", rest.DefaultUpdatedObjectInfo(&obj), nil, nil, true, &metav1.UpdateOptions{})
+				return err
+			},
+			expectedErr: kerrors.IsForbidden,
+		},
+		{
+			name: "update of a projected contentconfiguration in the workspace",
+			write: func(ctx context.Context, store *forwardingregistry.StoreFuncs) error {
+				obj := newCC("local-projected", contentFor, false)
+				_, _, err := store.Update(ctx, "local-projected", rest.DefaultUpdatedObjectInfo(&obj), nil, nil, false, &metav1.UpdateOptions{})
+				return err
+			},
+			expectedErr: kerrors.IsForbidden,
+		},
+		{
+			name: "update adding the content-for label",
+			write: func(ctx context.Context, store *forwardingregistry.StoreFuncs) error {
+				obj := newCC("local", contentFor, false)
+				_, _, err := store.Update(ctx, "local", rest.DefaultUpdatedObjectInfo(&obj), nil, nil, false, &metav1.UpdateOptions{})
+				return err
+			},
+			expectedErr: kerrors.IsForbidden,
+		},
+		{
+			name: "create with the content-for label",
+			write: func(ctx context.Context, store *forwardingregistry.StoreFuncs) error {
+				obj := newCC("new", contentFor, false)
+				_, err := store.Create(ctx, &obj, nil, &metav1.CreateOptions{})
+				return err
+			},
+			expectedErr: kerrors.IsForbidden,
+		},
+		{
+			name: "delete of a projected contentconfiguration in the workspace",
+			write: func(ctx context.Context, store *forwardingregistry.StoreFuncs) error {
+				_, _, err := store.Delete(ctx, "local-projected", nil, &metav1.DeleteOptions{})
+				return err
+			},
+			expectedErr: kerrors.IsForbidden,
+		},
+		{
+			name: "delete in the workspace",
+			write: func(ctx context.Context, store *forwardingregistry.StoreFuncs) error {
+				_, _, err := store.Delete(ctx, "local", nil, &metav1.DeleteOptions{})
+				return err
+			},
+			expectWritten: true,
+		},
+		{
+			name: "delete of a projected contentconfiguration",
+			write: func(ctx context.Context, store *forwardingregistry.StoreFuncs) error {
+				_, _, err := store.Delete(ctx, "projected", nil, &metav1.DeleteOptions{})
+				return err
+			},
+			expectedErr: kerrors.IsForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			gr := schema.GroupResource{Group: "ui.platform-mesh.io", Resource: "contentconfigurations"}
+			written := false
+
+			store := &forwardingregistry.StoreFuncs{}
+			store.GetterFunc = func(ctx context.Context, name string, options *metav1.GetOptions) (runtime.Object, error) {
+				switch name {
+				case "local":
+					obj := newCC(name, nil, false)
+					return &obj, nil
+				case "local-projected":
+					obj := newCC(name, contentFor, false)
+					return &obj, nil
+				}
+				return nil, kerrors.NewNotFound(gr, name)
+			}
+			store.CreaterFunc = func(ctx context.Context, obj runtime.Object, createValidation rest.ValidateObjectFunc, options *metav1.CreateOptions) (runtime.Object, error) {
+				if err := createValidation(ctx, obj); err != nil {
+					return nil, err
+				}
+				written = true
+				return obj, nil
+			}
+			store.UpdaterFunc = func(ctx context.Context, name string, objInfo rest.UpdatedObjectInfo, createValidation rest.ValidateObjectFunc, updateValidation rest.ValidateObjectUpdateFunc, forceAllowCreate bool, options *metav1.UpdateOptions) (runtime.Object, bool, error) {
+				old, err := store.Get(ctx, name, &metav1.GetOptions{})
+				if err != nil {
+					return nil, false, err
+				}
+				obj, err := objInfo.UpdatedObject(ctx, old)
+				if err != nil {
+					return nil, false, err
+				}
+				if err := updateValidation(ctx, obj, old); err != nil {
+					return nil, false, err
+				}
+				written = true
+				return obj, false, nil
+			}
+			store.GracefulDeleterFunc = func(ctx context.Context, name string, deleteValidation rest.ValidateObjectFunc, options *metav1.DeleteOptions) (runtime.Object, bool, error) {
+				written = true
+				return nil, true, nil
+			}
+
+			writer := &Writer{
+				ContentForLabel: testContentForLabel,
+				Projected: func(ctx context.Context, name string) (bool, error) {
+					return name == "projected", nil
+				},
+			}
+			writer.decorate(gr, schema.GroupKind{Group: gr.Group, Kind: "ContentConfiguration"}, fakeSchemaValidator{}, store)
+
+			err := tt.write(context.Background(), store)
+			if tt.expectedErr != nil {
+				require.Error(t, err)
+				assert.True(t, tt.expectedErr(err), "unexpected error: %v", err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectWritten, written)
+		})
+	}
+}
+
+func TestWriter_LimitsWrites(t *testing.T) {
+	t.Parallel()
+
+	gr := schema.GroupResource{Group: "ui.platform-mesh.io", Resource: "contentconfigurations"}
+	cfg := config.NewServiceConfig()
+	cfg.TenantRequestQPS = 1
+	cfg.TenantRequestBurst = 1
+
+	newStore := func() *forwardingregistry.StoreFuncs {
+		store := &forwardingregistry.StoreFuncs{}
+		store.GetterFunc = func(ctx context.Context, name string, options *metav1.GetOptions) (runtime.Object, error) {
+			obj := newCC(name, nil, false)
+			return &obj, nil
+		}
+		store.UpdaterFunc = func(ctx context.Context, name string, objInfo rest.UpdatedObjectInfo, createValidation rest.ValidateObjectFunc, updateValidation rest.ValidateObjectUpdateFunc, forceAllowCreate bool, options *metav1.UpdateOptions) (runtime.Object, bool, error) {
+			// like the forwarding storage, read the current object first
+			old, err := store.Get(ctx, name, &metav1.GetOptions{})
+			if err != nil {
+				return nil, false, err
+			}
+			obj, err := objInfo.UpdatedObject(ctx, old)
+			if err != nil {
+				return nil, false, err
+			}
+			return obj, false, updateValidation(ctx, obj, old)
+		}
+		store.GracefulDeleterFunc = func(ctx context.Context, name string, deleteValidation rest.ValidateObjectFunc, options *metav1.DeleteOptions) (runtime.Object, bool, error) {
+			return nil, true, nil
+		}
+
+		writer := &Writer{Limiter: newTenantLimiter(cfg, clocktesting.NewFakeClock(time.Now()))}
+		writer.decorate(gr, schema.GroupKind{Group: gr.Group, Kind: "ContentConfiguration"}, fakeSchemaValidator{}, store)
+		return store
+	}
+
+	tests := []struct {
+		name  string
+		write func(ctx context.Context, store *forwardingregistry.StoreFuncs) error
+	}{
+		{
+			name: "delete",
+			write: func(ctx context.Context, store *forwardingregistry.StoreFuncs) error {
+				_, _, err := store.Delete(ctx, "local", nil, &metav1.DeleteOptions{})
+				return err
+			},
+		},
+		{
+			name: "update",
+			write: func(ctx context.Context, store *forwardingregistry.StoreFuncs) error {
+				obj := newCC("local", nil, false)
+				_, _, err := store.Update(ctx, "local", rest.DefaultUpdatedObjectInfo(&obj), nil, nil, false, &metav1.UpdateOptions{})
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			store := newStore()
+			ctx := genericapirequest.WithUser(context.Background(), &user.DefaultInfo{Name: "alice"})
+
+			// the gets of a write are not limited, so a burst of one admits it
+			require.NoError(t, tt.write(ctx, store))
+
+			err := tt.write(ctx, store)
+			require.True(t, kerrors.IsTooManyRequests(err), "expected too many requests, got %v", err)
+		})
+	}
+}
+
+func TestProjectedContentConfiguration(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.NewServiceConfig()
+	contentFor := func(export string) map[string]string {
+		return map[string]string{cfg.ContentForLabel: export}
+	}
+
+	client := &fakeDynamicClusterClient{objects: map[logicalcluster.Path]map[string][]unstructured.Unstructured{
+		logicalcluster.NewPath("team-a"): {
+			"apibindings": {
+				newAPIBinding("cached", "cached-export", "cached-export-ws"),
+				newAPIBinding("live", "live-export", "live-export-ws"),
+				newAPIBinding("incomplete", "incomplete-export", ""),
+			},
+		},
+		logicalcluster.NewPath("live-export-ws"): {
+			ContentConfigurationGVR.Resource: {
+				newCC("from-live-export", contentFor("live-export"), true),
+				newCC("for-other-export", contentFor("other-export"), true),
+			},
+		},
+		logicalcluster.NewPath("provider-ws"): {
+			ContentConfigurationGVR.Resource: {newCC("from-live-provider", nil, true)},
+		},
+	}}
+	cache := &fakeContentConfigurationCache{ccsByCluster: map[logicalcluster.Name][]unstructured.Unstructured{
+		"cached-export-ws": {newCC("from-cached-export", contentFor("cached-export"), true)},
+		"cached-provider":  {newCC("from-cached-provider", nil, true)},
+	}}
+	isProjected := ProjectedContentConfiguration(client, cfg, testProviders(cfg, "cached-provider", "provider-ws"), cache)
+
+	tests := []struct {
+		name     string
+		expected bool
+	}{
+		{name: "from-cached-export", expected: true},
+		{name: "from-live-export", expected: true},
+		{name: "from-cached-provider", expected: true},
+		{name: "from-live-provider", expected: true},
+		{name: "for-other-export"},
+		{name: "unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := genericapirequest.WithCluster(context.Background(), genericapirequest.Cluster{Name: "team-a"})
+			projected, err := isProjected(ctx, tt.name)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, projected)
+		})
+	}
+}